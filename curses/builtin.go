@@ -0,0 +1,175 @@
+package curses
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb"
+	orbGeo "github.com/paulmach/orb/geo"
+)
+
+// the seeker counts as being in a station when the nearest station is closer than this
+const stationRadius = 100.0
+
+const (
+	// how far from the seeker the hider can send them with the travel agent
+	travelAgentRadius = 250.0
+	// the seeker counts as being at the destination of the travel agent when they are closer than this
+	destinationRadius = 25.0
+	// how long the seeker has to stay at the destination of the travel agent
+	destinationStayTime = 5 * time.Minute
+)
+
+// the effects of the built-in curses by the key in Card.Effect
+var effects = map[string]Effect{
+	// the server doesn't know where bridges are, so the seeker resolves the curse once they are under one
+	"bridgeTroll": blockAllQuestions{reason: "the next question has to be asked from under a bridge, the seeker resolves the curse once they are there"},
+	// the server can't see doors, the seeker has to be trusted
+	"jammedDoor":   noEffect{},
+	"travelAgent":  travelAgent{},
+	"drainedBrain": drainedBrain{},
+	"zoologist":    blockAllQuestions{reason: "no questions can be asked until the seeker sent a picture of an animal of the same class"},
+	// the server can't tell a right turn from the location updates, the seeker has to be trusted
+	"rightTurn":     noEffect{},
+	"censusTaker":   blockAllQuestions{reason: "the seeker has to estimate the population of the Bezirk first"},
+	"urbanExplorer": urbanExplorer{},
+	"birdGuide":     blockAllQuestions{reason: "no questions can be asked until the seeker filmed a bird for longer than the hider"},
+}
+
+// blocks the question categories the hider chose
+type drainedBrain struct {
+	noEffect
+}
+
+func (e drainedBrain) Validate(lobby models.Lobby, parameters sharedModels.CurseParameters) error {
+	if len(parameters.Categories) != 3 {
+		return fmt.Errorf("%w: exactly three question categories have to be chosen", ErrInvalidCurseParameters)
+	}
+	for index, category := range parameters.Categories {
+		if !slices.Contains(sharedModels.QuestionCategories, category) {
+			return fmt.Errorf("%w: unknown question category %s", ErrInvalidCurseParameters, category)
+		}
+		if slices.Contains(parameters.Categories[:index], category) {
+			return fmt.Errorf("%w: question category %s was chosen twice", ErrInvalidCurseParameters, category)
+		}
+	}
+	return nil
+}
+
+func (e drainedBrain) OnPhaseChange(curse *models.Card, lobby models.Lobby) bool {
+	return endWithRun(curse, lobby)
+}
+
+func (e drainedBrain) OnQuestionAsk(curse models.Card, ask QuestionAsk) error {
+	parameters := curse.CurseParameters()
+	if !slices.Contains(parameters.Categories, ask.Info.Category) {
+		return nil
+	}
+	var categoryNames []string
+	for _, category := range parameters.Categories {
+		categoryNames = append(categoryNames, string(category))
+	}
+	return blocked(curse, string(ask.Info.Category)+" questions can't be asked, the blocked categories are "+strings.Join(categoryNames, ", "))
+}
+
+// blocks every question while the curse is active
+type blockAllQuestions struct {
+	noEffect
+	reason string
+}
+
+func (e blockAllQuestions) OnQuestionAsk(curse models.Card, ask QuestionAsk) error {
+	return blocked(curse, e.reason)
+}
+
+// the seeker can't ask questions from train stations
+type urbanExplorer struct {
+	noEffect
+}
+
+func (e urbanExplorer) OnPhaseChange(curse *models.Card, lobby models.Lobby) bool {
+	return endWithRun(curse, lobby)
+}
+
+func (e urbanExplorer) OnQuestionAsk(curse models.Card, ask QuestionAsk) error {
+	// yes, longitude comes first, look at https://pkg.go.dev/github.com/paulmach/orb#Point
+	seekerPoint := orb.Point{ask.Lobby.SeekerLon, ask.Lobby.SeekerLat}
+	station, distance, found := ask.Data.RailwayStationIndex.Nearest(seekerPoint)
+	if !found || distance > stationRadius {
+		return nil
+	}
+	stationName := station.Name
+	if stationName == "" {
+		stationName = "a station"
+	}
+	return blocked(curse, "questions can't be asked from train stations and the seeker is at "+stationName)
+}
+
+// the hider chooses a place close to the seeker, the seeker can't ask questions until they stayed there for a while
+type travelAgent struct {
+	noEffect
+}
+
+func (e travelAgent) Validate(lobby models.Lobby, parameters sharedModels.CurseParameters) error {
+	// yes, longitude comes first, look at https://pkg.go.dev/github.com/paulmach/orb#Point
+	seekerPoint := orb.Point{lobby.SeekerLon, lobby.SeekerLat}
+	hiderPoint := orb.Point{lobby.HiderLon, lobby.HiderLat}
+	destination := parameters.Destination
+	if destination == (orb.Point{}) {
+		return fmt.Errorf("%w: a destination has to be chosen", ErrInvalidCurseParameters)
+	}
+	if orbGeo.DistanceHaversine(seekerPoint, destination) > travelAgentRadius {
+		return fmt.Errorf("%w: the destination has to be within %.0fm of the seeker", ErrInvalidCurseParameters, travelAgentRadius)
+	}
+	if orbGeo.DistanceHaversine(hiderPoint, destination) <= orbGeo.DistanceHaversine(hiderPoint, seekerPoint) {
+		return fmt.Errorf("%w: the destination has to be farther away from the hider than the seeker", ErrInvalidCurseParameters)
+	}
+	return nil
+}
+
+func (e travelAgent) OnQuestionAsk(curse models.Card, ask QuestionAsk) error {
+	return blocked(curse, "no questions can be asked until the seeker stayed at the place the hider chose for "+destinationStayTime.String())
+}
+
+// resolves the curse once the seeker stayed at the destination long enough, leaving it starts the time again
+func (e travelAgent) OnLocationUpdate(curse *models.Card, lobby models.Lobby, userID uint) HookResult {
+	if userID != lobby.SeekerID {
+		return HookResult{}
+	}
+	seekerPoint := orb.Point{lobby.SeekerLon, lobby.SeekerLat}
+	if orbGeo.DistanceHaversine(seekerPoint, curse.CurseParameters().Destination) > destinationRadius {
+		if curse.DestinationReachedTime.IsZero() {
+			return HookResult{}
+		}
+		curse.DestinationReachedTime = time.Time{}
+		return HookResult{Save: true}
+	}
+
+	now := time.Now()
+	if curse.DestinationReachedTime.IsZero() {
+		curse.DestinationReachedTime = now
+		return HookResult{Save: true}
+	}
+	if now.Sub(curse.DestinationReachedTime) < destinationStayTime {
+		return HookResult{}
+	}
+	return HookResult{
+		State:       sharedModels.CurseResolved,
+		Description: "Seeker stayed at the place the hider chose for " + destinationStayTime.String(),
+	}
+}
+
+// ends curses that last for the remainder of the run once the lobby left it,
+// either because the run time is over or because the seeker reached the endgame
+func endWithRun(curse *models.Card, lobby models.Lobby) bool {
+	if lobby.Phase == sharedModels.PhaseRun {
+		return false
+	}
+	curse.CurseState = sharedModels.CurseResolved
+	return true
+}
@@ -0,0 +1,95 @@
+package curses
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"gorm.io/gorm"
+
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+var ErrCastingCostNotPaid error = errors.New("Casting cost isn't paid")
+
+// checks that the payment covers the casting cost of the card and returns the cards of the hand that get discarded for it.
+// the hand of the lobby has to be loaded
+func CheckPayment(db *gorm.DB, lobby models.Lobby, curse models.Card, payment sharedModels.CastingCostPayment) ([]models.Card, error) {
+	var discardedCards []models.Card
+	for index, cardID := range payment.DiscardedCardIDs {
+		if cardID == curse.ID {
+			return nil, fmt.Errorf("%w: the card can't pay for itself", ErrCastingCostNotPaid)
+		}
+		if slices.Contains(payment.DiscardedCardIDs[:index], cardID) {
+			return nil, fmt.Errorf("%w: card %d was discarded twice", ErrCastingCostNotPaid, cardID)
+		}
+		cardIndex := slices.IndexFunc(lobby.HiderDeck, func(card models.Card) bool {
+			return card.ID == cardID
+		})
+		if cardIndex == -1 {
+			return nil, fmt.Errorf("%w: card %d isn't in the hand of the hider", ErrCastingCostNotPaid, cardID)
+		}
+		discardedCards = append(discardedCards, lobby.HiderDeck[cardIndex])
+	}
+
+	cost := curse.CastingCost
+	if cost.Type != sharedModels.CostPhoto && payment.PhotoID != 0 {
+		return nil, fmt.Errorf("%w: the card doesn't need a photo", ErrCastingCostNotPaid)
+	}
+
+	switch cost.Type {
+	case sharedModels.CostNone, sharedModels.CostPhoto:
+		if len(discardedCards) != 0 {
+			return nil, fmt.Errorf("%w: the card doesn't need discarded cards", ErrCastingCostNotPaid)
+		}
+	case sharedModels.CostDiscard:
+		if uint(len(discardedCards)) != cost.Amount {
+			return nil, fmt.Errorf("%w: %d cards have to be discarded", ErrCastingCostNotPaid, cost.Amount)
+		}
+	case sharedModels.CostDiscardHand:
+		// everything except the card itself
+		if len(discardedCards) != len(lobby.HiderDeck)-1 {
+			return nil, fmt.Errorf("%w: the whole hand has to be discarded", ErrCastingCostNotPaid)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown casting cost %s", ErrCastingCostNotPaid, cost.Type)
+	}
+
+	if cost.Type == sharedModels.CostPhoto {
+		err := checkPhotoPayment(db, lobby.ID, payment.PhotoID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return discardedCards, nil
+}
+
+// the photo has to belong to the lobby and can't be used for anything else yet
+func checkPhotoPayment(db *gorm.DB, lobbyID uint, photoID uint) error {
+	if photoID == 0 {
+		return fmt.Errorf("%w: a photo has to be uploaded", ErrCastingCostNotPaid)
+	}
+	var photo models.Photo
+	result := db.Where("lobby_id = ?", lobbyID).Limit(1).Find(&photo, photoID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: photo %d doesn't exist", ErrCastingCostNotPaid, photoID)
+	}
+
+	var cardUses, questionUses int64
+	result = db.Model(&models.Card{}).Where("photo_id = ?", photoID).Count(&cardUses)
+	if result.Error != nil {
+		return result.Error
+	}
+	result = db.Model(&models.AskedQuestion{}).Where("photo_id = ?", photoID).Count(&questionUses)
+	if result.Error != nil {
+		return result.Error
+	}
+	if cardUses+questionUses > 0 {
+		return fmt.Errorf("%w: photo %d was already used", ErrCastingCostNotPaid, photoID)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package curses
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+func TestCheckPayment(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(&models.Card{}, &models.Photo{}, &models.AskedQuestion{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// photo 1 belongs to the lobby, photo 2 to another lobby and photo 3 already answered a question
+	photos := []models.Photo{{LobbyID: 1}, {LobbyID: 2}, {LobbyID: 1}}
+	for index := range photos {
+		db.Create(&photos[index])
+	}
+	db.Create(&models.AskedQuestion{LobbyID: 1, PhotoID: photos[2].ID})
+
+	lobby := models.Lobby{HiderDeck: []models.Card{{}, {}, {}, {}}}
+	lobby.ID = 1
+	for index := range lobby.HiderDeck {
+		lobby.HiderDeck[index].ID = uint(index + 1)
+	}
+	curseWithCost := func(cost sharedModels.CastingCost) models.Card {
+		curse := models.Card{CastingCost: cost}
+		curse.ID = 1
+		return curse
+	}
+
+	tests := []struct {
+		name      string
+		cost      sharedModels.CastingCost
+		payment   sharedModels.CastingCostPayment
+		discarded int
+		err       bool
+	}{
+		{name: "free curse", cost: sharedModels.CastingCost{Type: sharedModels.CostNone}},
+		{
+			name:    "free curse with discarded cards",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostNone},
+			payment: sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{2}},
+			err:     true,
+		},
+		{
+			name:      "discard the right amount",
+			cost:      sharedModels.CastingCost{Type: sharedModels.CostDiscard, Amount: 2},
+			payment:   sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{2, 3}},
+			discarded: 2,
+		},
+		{
+			name:    "discard too few",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostDiscard, Amount: 2},
+			payment: sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{2}},
+			err:     true,
+		},
+		{
+			name:    "discard the same card twice",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostDiscard, Amount: 2},
+			payment: sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{2, 2}},
+			err:     true,
+		},
+		{
+			name:    "discard the curse itself",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostDiscard, Amount: 1},
+			payment: sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{1}},
+			err:     true,
+		},
+		{
+			name:    "discard a card that isn't in the hand",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostDiscard, Amount: 1},
+			payment: sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{9}},
+			err:     true,
+		},
+		{
+			name:      "discard the whole hand",
+			cost:      sharedModels.CastingCost{Type: sharedModels.CostDiscardHand},
+			payment:   sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{2, 3, 4}},
+			discarded: 3,
+		},
+		{
+			name:    "discard only part of the hand",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostDiscardHand},
+			payment: sharedModels.CastingCostPayment{DiscardedCardIDs: []uint{2, 3}},
+			err:     true,
+		},
+		{
+			name:    "photo of the lobby",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostPhoto},
+			payment: sharedModels.CastingCostPayment{PhotoID: photos[0].ID},
+		},
+		{
+			name: "missing photo",
+			cost: sharedModels.CastingCost{Type: sharedModels.CostPhoto},
+			err:  true,
+		},
+		{
+			name:    "photo of another lobby",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostPhoto},
+			payment: sharedModels.CastingCostPayment{PhotoID: photos[1].ID},
+			err:     true,
+		},
+		{
+			name:    "photo that answered a question",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostPhoto},
+			payment: sharedModels.CastingCostPayment{PhotoID: photos[2].ID},
+			err:     true,
+		},
+		{
+			name:    "photo for a curse that doesn't need one",
+			cost:    sharedModels.CastingCost{Type: sharedModels.CostNone},
+			payment: sharedModels.CastingCostPayment{PhotoID: photos[0].ID},
+			err:     true,
+		},
+		{
+			name: "unknown casting cost",
+			cost: sharedModels.CastingCost{Type: "dance"},
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			discardedCards, err := CheckPayment(db, lobby, curseWithCost(test.cost), test.payment)
+			if test.err {
+				if !errors.Is(err, ErrCastingCostNotPaid) {
+					t.Fatalf("got error %v, want %v", err, ErrCastingCostNotPaid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(discardedCards) != test.discarded {
+				t.Errorf("got %d discarded cards, want %d", len(discardedCards), test.discarded)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package curses
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+var ErrBlockedByCurse error = errors.New("Blocked by an active curse")
+
+var ErrInvalidCurseParameters error = errors.New("Invalid curse parameters")
+
+// everything a curse can look at when the seeker asks a question
+type QuestionAsk struct {
+	Lobby models.Lobby
+	Info  sharedModels.QuestionInfo
+	Data  geo.ProcessedData
+}
+
+// enforces the rules of a curse on the server. the hooks only get called while the curse is active
+type Effect interface {
+	// checks the parameters the hider chose when playing the curse
+	Validate(lobby models.Lobby, parameters sharedModels.CurseParameters) error
+	// returns an error with the reason if the seeker can't ask the question
+	OnQuestionAsk(curse models.Card, ask QuestionAsk) error
+	// gets called after a player moved
+	OnLocationUpdate(curse *models.Card, lobby models.Lobby, userID uint) HookResult
+	// gets called after the lobby moved to a new phase, returns whether the curse changed and has to be saved
+	OnPhaseChange(curse *models.Card, lobby models.Lobby) bool
+}
+
+// what a hook changed on a curse. a new state goes into the history with the description,
+// other changes to the fields of the curse only get saved
+type HookResult struct {
+	Save        bool
+	State       sharedModels.CurseState
+	Description string
+}
+
+// hooks that don't do anything, effects only override what they need
+type noEffect struct{}
+
+func (e noEffect) Validate(lobby models.Lobby, parameters sharedModels.CurseParameters) error {
+	return nil
+}
+
+func (e noEffect) OnQuestionAsk(curse models.Card, ask QuestionAsk) error {
+	return nil
+}
+
+func (e noEffect) OnLocationUpdate(curse *models.Card, lobby models.Lobby, userID uint) HookResult {
+	return HookResult{}
+}
+
+func (e noEffect) OnPhaseChange(curse *models.Card, lobby models.Lobby) bool {
+	return false
+}
+
+// the effect of the card, cards without a known effect get one that doesn't enforce anything
+func EffectOf(card models.Card) Effect {
+	effect, ok := effects[card.Effect]
+	if !ok {
+		if card.Effect != "" {
+			log.Warn().Msg("card " + card.Title + " has unknown effect " + card.Effect)
+		}
+		return noEffect{}
+	}
+	return effect
+}
+
+// whether the server knows how to enforce the effect, e.g. for validating deck packs
+func IsKnownEffect(key string) bool {
+	_, ok := effects[key]
+	return ok
+}
+
+// validates the parameters of a curse that is about to be played and stores them on the card
+func Prepare(curse *models.Card, lobby models.Lobby, parameters sharedModels.CurseParameters) error {
+	err := EffectOf(*curse).Validate(lobby, parameters)
+	if err != nil {
+		return err
+	}
+	marshalledParameters, err := json.Marshal(parameters)
+	if err != nil {
+		return err
+	}
+	curse.Parameters = string(marshalledParameters)
+	return nil
+}
+
+// returns the reason why the question can't be asked if an active curse of the lobby forbids it.
+// the played curses of the lobby have to be loaded
+func CheckQuestion(ask QuestionAsk) error {
+	now := time.Now()
+	for _, curse := range ask.Lobby.PlayedCurseList {
+		if !curse.IsActive(now) {
+			continue
+		}
+		err := EffectOf(curse).OnQuestionAsk(curse, ask)
+		if err != nil {
+			log.Info().Msg("curse " + fmt.Sprint(curse.ID) + " blocks question " + ask.Info.ID + " in lobby " + ask.Lobby.Token)
+			return err
+		}
+	}
+	return nil
+}
+
+// calls the location hooks of the active curses of the lobby and stores what they changed
+func OnLocationUpdate(db *gorm.DB, lobby models.Lobby, userID uint) error {
+	activeCurses, err := loadActiveCurses(db, lobby.ID)
+	if err != nil {
+		return err
+	}
+	for index := range activeCurses {
+		curse := &activeCurses[index]
+		hookResult := EffectOf(*curse).OnLocationUpdate(curse, lobby, userID)
+		if hookResult.State != "" && hookResult.State != curse.CurseState {
+			log.Info().Msg("curse " + fmt.Sprint(curse.ID) + " moved to state " + string(hookResult.State) + " in lobby " + fmt.Sprint(lobby.ID))
+			err := SetState(db, curse, hookResult.State, hookResult.Description)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		if hookResult.Save {
+			result := db.Save(curse)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+	}
+	return nil
+}
+
+// calls the phase hooks of the active curses of the lobby after it moved to a new phase.
+// curses that ended themselves go into the history
+func OnPhaseChange(db *gorm.DB, lobby models.Lobby) error {
+	activeCurses, err := loadActiveCurses(db, lobby.ID)
+	if err != nil {
+		return err
+	}
+	for index := range activeCurses {
+		curse := &activeCurses[index]
+		if !EffectOf(*curse).OnPhaseChange(curse, lobby) {
+			continue
+		}
+		if curse.CurseState != sharedModels.CurseActive {
+			log.Info().Msg("curse " + fmt.Sprint(curse.ID) + " moved to state " + string(curse.CurseState) + " in lobby " + fmt.Sprint(lobby.ID))
+			err = SetState(db, curse, curse.CurseState, "Curse ended with the run")
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		result := db.Save(curse)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+// the hooks load the curses from the db because their callers don't always have the whole lobby
+func loadActiveCurses(db *gorm.DB, lobbyID uint) ([]models.Card, error) {
+	var playedCurses []models.Card
+	result := db.Where("played_curse_id = ?", lobbyID).Find(&playedCurses)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	now := time.Now()
+	return slices.DeleteFunc(playedCurses, func(curse models.Card) bool {
+		return !curse.IsActive(now)
+	}), nil
+}
+
+// the error the seeker gets when a curse blocks a question
+func blocked(curse models.Card, reason string) error {
+	return fmt.Errorf("%w: %s (%s)", ErrBlockedByCurse, reason, curse.Title)
+}
@@ -0,0 +1,39 @@
+package curses
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/jkulzer/fib-server/events"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+// moves the curse to a new state, stores it and adds the change to the history
+func SetState(db *gorm.DB, curse *models.Card, state sharedModels.CurseState, description string) error {
+	curse.CurseState = state
+	result := db.Save(curse)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	historyItem := models.HistoryInDB{
+		LobbyID:     curse.PlayedCurseID,
+		Title:       curse.Title,
+		Description: description,
+	}
+	// the photo the casting cost was paid with gets shown to the seeker with the curse
+	if state == sharedModels.CurseActive {
+		historyItem.PhotoID = curse.PhotoID
+	}
+	result = db.Create(&historyItem)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if state == sharedModels.CurseActive {
+		events.Queue(db, curse.PlayedCurseID, sharedModels.EventCursePlayed, curse.DTO())
+	} else {
+		events.Queue(db, curse.PlayedCurseID, sharedModels.EventCurseUpdate, curse.DTO())
+	}
+	return nil
+}
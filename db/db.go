@@ -6,6 +6,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/jkulzer/fib-server/events"
 	"github.com/jkulzer/fib-server/models"
 )
 
@@ -15,6 +16,12 @@ func InitDB() *gorm.DB {
 		log.Err(err).Msg("failed to create/open db")
 	}
 
+	// events of db writes only get published once the writes are committed
+	err = events.RegisterCallbacks(db)
+	if err != nil {
+		log.Err(err).Msg("failed to register event callbacks")
+	}
+
 	db.AutoMigrate(&models.UserAccount{})
 	db.AutoMigrate(&models.Session{})
 	db.AutoMigrate(&models.Lobby{})
@@ -22,6 +29,9 @@ func InitDB() *gorm.DB {
 	db.AutoMigrate(&models.CardDraw{})
 	db.AutoMigrate(&models.CurrentDraw{})
 	db.AutoMigrate(&models.Card{})
+	db.AutoMigrate(&models.ScheduledEvent{})
+	db.AutoMigrate(&models.Photo{})
+	db.AutoMigrate(&models.AskedQuestion{})
 
 	db.Session(&gorm.Session{FullSaveAssociations: true})
 
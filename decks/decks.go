@@ -0,0 +1,247 @@
+package decks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/curses"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+var ErrInvalidDeckPack error = errors.New("Invalid deck pack")
+
+// the names of the card types in pack files
+var cardTypes = map[string]sharedModels.CardType{
+	"timebonus":     sharedModels.TimebonusCard,
+	"curse":         sharedModels.CurseCard,
+	"discard1Draw2": sharedModels.Discard1Draw2Card,
+	"discard2Draw3": sharedModels.Discard2Draw3Card,
+	"veto":          sharedModels.VetoCard,
+	"randomize":     sharedModels.RandomizeCard,
+	"duplicate":     sharedModels.DuplicateCard,
+	"move":          sharedModels.MoveCard,
+}
+
+// a set of cards a lobby can play with
+type Pack struct {
+	// the file name without the extension, used in the lobby settings
+	Key         string
+	Name        string
+	Description string
+	// every copy of a card is its own entry
+	Cards []sharedModels.Card
+}
+
+func (p Pack) Info() sharedModels.DeckPackInfo {
+	return sharedModels.DeckPackInfo{
+		Key:         p.Key,
+		Name:        p.Name,
+		Description: p.Description,
+		CardCount:   len(p.Cards),
+	}
+}
+
+// the loaded packs by their key
+type Packs map[string]Pack
+
+// the infos of all packs sorted by key
+func (p Packs) Infos() []sharedModels.DeckPackInfo {
+	infos := []sharedModels.DeckPackInfo{}
+	for _, key := range slices.Sorted(maps.Keys(p)) {
+		infos = append(infos, p[key].Info())
+	}
+	return infos
+}
+
+// a pack how it's written in a JSON or YAML file
+type packFile struct {
+	Name        string           `yaml:"Name"`
+	Description string           `yaml:"Description"`
+	Cards       []cardDefinition `yaml:"Cards"`
+}
+
+// durations are written like "30m" because nanoseconds are hard to read
+type cardDefinition struct {
+	// how many copies of the card are in the pack, defaults to 1
+	Count                  int                   `yaml:"Count"`
+	Title                  string                `yaml:"Title"`
+	Description            string                `yaml:"Description"`
+	Type                   string                `yaml:"Type"`
+	ExpirationDuration     string                `yaml:"ExpirationDuration"`
+	BonusTime              string                `yaml:"BonusTime"`
+	PenaltyTime            string                `yaml:"PenaltyTime"`
+	Effect                 string                `yaml:"Effect"`
+	Resolvable             bool                  `yaml:"Resolvable"`
+	CastingCostDescription string                `yaml:"CastingCostDescription"`
+	CastingCost            castingCostDefinition `yaml:"CastingCost"`
+}
+
+type castingCostDefinition struct {
+	Type   sharedModels.CastingCostType `yaml:"Type"`
+	Amount uint                         `yaml:"Amount"`
+}
+
+// loads every .json, .yaml and .yml file in the directory as a pack
+func LoadPacks(dir string) (Packs, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	packs := make(Packs)
+	for _, entry := range entries {
+		extension := filepath.Ext(entry.Name())
+		if entry.IsDir() || (extension != ".json" && extension != ".yaml" && extension != ".yml") {
+			continue
+		}
+		key := strings.TrimSuffix(entry.Name(), extension)
+		if _, exists := packs[key]; exists {
+			return nil, fmt.Errorf("%w: there is more than one pack called %s", ErrInvalidDeckPack, key)
+		}
+		pack, err := LoadPack(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		packs[key] = pack
+		log.Info().Msg("loaded deck pack " + key + " with " + fmt.Sprint(len(pack.Cards)) + " cards")
+	}
+
+	if _, ok := packs[sharedModels.DefaultDeckPack]; !ok {
+		return nil, fmt.Errorf("%w: the pack %s is missing in %s", ErrInvalidDeckPack, sharedModels.DefaultDeckPack, dir)
+	}
+	return packs, nil
+}
+
+// loads and validates a single pack, the format depends on the file extension
+func LoadPack(path string) (Pack, error) {
+	var file packFile
+
+	packBytes, err := os.ReadFile(path)
+	if err != nil {
+		return Pack{}, err
+	}
+	if filepath.Ext(path) == ".json" {
+		err = json.Unmarshal(packBytes, &file)
+	} else {
+		err = yaml.Unmarshal(packBytes, &file)
+	}
+	if err != nil {
+		return Pack{}, err
+	}
+
+	if file.Name == "" || len(file.Cards) == 0 {
+		return Pack{}, fmt.Errorf("%w: a pack needs a name and cards", ErrInvalidDeckPack)
+	}
+	pack := Pack{
+		Key:         strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+		Name:        file.Name,
+		Description: file.Description,
+	}
+	for _, definition := range file.Cards {
+		card, err := definition.card()
+		if err != nil {
+			return Pack{}, err
+		}
+		count := definition.Count
+		if count == 0 {
+			count = 1
+		}
+		if count < 0 {
+			return Pack{}, fmt.Errorf("%w: card %s has a negative count", ErrInvalidDeckPack, card.Title)
+		}
+		for i := 0; i < count; i++ {
+			pack.Cards = append(pack.Cards, card)
+		}
+	}
+	return pack, nil
+}
+
+// checks the definition against the card types and effects the server knows
+func (d cardDefinition) card() (sharedModels.Card, error) {
+	if d.Title == "" {
+		return sharedModels.Card{}, fmt.Errorf("%w: every card needs a title", ErrInvalidDeckPack)
+	}
+	cardType, ok := cardTypes[d.Type]
+	if !ok {
+		return sharedModels.Card{}, fmt.Errorf("%w: card %s has unknown type %s", ErrInvalidDeckPack, d.Title, d.Type)
+	}
+
+	card := sharedModels.Card{
+		Title:                  d.Title,
+		Description:            d.Description,
+		Type:                   cardType,
+		Effect:                 d.Effect,
+		Resolvable:             d.Resolvable,
+		CastingCostDescription: d.CastingCostDescription,
+		CastingCost: sharedModels.CastingCost{
+			Type:   d.CastingCost.Type,
+			Amount: d.CastingCost.Amount,
+		},
+	}
+	var err error
+	card.ExpirationDuration, err = parseDuration(d.ExpirationDuration)
+	if err != nil {
+		return sharedModels.Card{}, fmt.Errorf("%w: card %s has an invalid expiration duration", ErrInvalidDeckPack, d.Title)
+	}
+	card.BonusTime, err = parseDuration(d.BonusTime)
+	if err != nil {
+		return sharedModels.Card{}, fmt.Errorf("%w: card %s has an invalid bonus time", ErrInvalidDeckPack, d.Title)
+	}
+	card.PenaltyTime, err = parseDuration(d.PenaltyTime)
+	if err != nil {
+		return sharedModels.Card{}, fmt.Errorf("%w: card %s has an invalid penalty time", ErrInvalidDeckPack, d.Title)
+	}
+
+	if cardType == sharedModels.TimebonusCard && card.BonusTime <= 0 {
+		return sharedModels.Card{}, fmt.Errorf("%w: time bonus %s needs a bonus time", ErrInvalidDeckPack, d.Title)
+	}
+	if cardType != sharedModels.CurseCard && (card.Effect != "" || card.Resolvable || card.ExpirationDuration != 0 || card.PenaltyTime != 0) {
+		return sharedModels.Card{}, fmt.Errorf("%w: only curses can have an effect, an expiration or a penalty", ErrInvalidDeckPack)
+	}
+	if card.Effect != "" && !curses.IsKnownEffect(card.Effect) {
+		return sharedModels.Card{}, fmt.Errorf("%w: card %s has unknown effect %s", ErrInvalidDeckPack, d.Title, d.Effect)
+	}
+
+	switch card.CastingCost.Type {
+	case sharedModels.CostNone, sharedModels.CostDiscardHand, sharedModels.CostPhoto:
+		if card.CastingCost.Amount != 0 {
+			return sharedModels.Card{}, fmt.Errorf("%w: casting cost of card %s can't have an amount", ErrInvalidDeckPack, d.Title)
+		}
+	case sharedModels.CostDiscard:
+		if card.CastingCost.Amount == 0 {
+			return sharedModels.Card{}, fmt.Errorf("%w: casting cost of card %s needs an amount", ErrInvalidDeckPack, d.Title)
+		}
+	default:
+		return sharedModels.Card{}, fmt.Errorf("%w: card %s has unknown casting cost %s", ErrInvalidDeckPack, d.Title, card.CastingCost.Type)
+	}
+	if cardType == sharedModels.TimebonusCard && card.CastingCost.Type != sharedModels.CostNone {
+		return sharedModels.Card{}, fmt.Errorf("%w: time bonus %s can't be played, so it can't have a casting cost", ErrInvalidDeckPack, d.Title)
+	}
+	return card, nil
+}
+
+// an empty duration means none
+func parseDuration(duration string) (time.Duration, error) {
+	if duration == "" {
+		return 0, nil
+	}
+	parsedDuration, err := time.ParseDuration(duration)
+	if err != nil {
+		return 0, err
+	}
+	if parsedDuration < 0 {
+		return 0, fmt.Errorf("negative duration %s", duration)
+	}
+	return parsedDuration, nil
+}
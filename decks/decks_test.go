@@ -0,0 +1,187 @@
+package decks
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+func TestLoadPack(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		pack  string
+		cards int
+		err   bool
+	}{
+		{
+			name: "yaml pack",
+			file: "pack.yaml",
+			pack: `
+Name: Test
+Cards:
+  - Title: Curse of the jammed door
+    Type: curse
+    ExpirationDuration: 30m
+    Effect: jammedDoor
+  - Title: 5 Minute Bonus
+    Type: timebonus
+    BonusTime: 5m
+    Count: 3
+  - Title: Veto
+    Type: veto
+    CastingCost:
+      Type: discard
+      Amount: 1
+`,
+			cards: 5,
+		},
+		{
+			name:  "json pack",
+			file:  "pack.json",
+			pack:  `{"Name": "Test", "Cards": [{"Title": "Randomize", "Type": "randomize", "Count": 2}]}`,
+			cards: 2,
+		},
+		{
+			name: "invalid yaml",
+			file: "pack.yaml",
+			pack: "Name: [Test",
+			err:  true,
+		},
+		{
+			name: "missing name",
+			file: "pack.yaml",
+			pack: "Cards:\n  - Title: Veto\n    Type: veto\n",
+			err:  true,
+		},
+		{
+			name: "no cards",
+			file: "pack.yaml",
+			pack: "Name: Test\n",
+			err:  true,
+		},
+		{
+			name: "card without title",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Type: veto\n",
+			err:  true,
+		},
+		{
+			name: "unknown card type",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Joker\n    Type: joker\n",
+			err:  true,
+		},
+		{
+			name: "unknown effect",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Curse\n    Type: curse\n    Effect: teleport\n",
+			err:  true,
+		},
+		{
+			name: "effect on a power-up",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Veto\n    Type: veto\n    Effect: jammedDoor\n",
+			err:  true,
+		},
+		{
+			name: "invalid duration",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Curse\n    Type: curse\n    ExpirationDuration: half an hour\n",
+			err:  true,
+		},
+		{
+			name: "negative duration",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Curse\n    Type: curse\n    PenaltyTime: -5m\n",
+			err:  true,
+		},
+		{
+			name: "time bonus without bonus time",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Bonus\n    Type: timebonus\n",
+			err:  true,
+		},
+		{
+			name: "time bonus with casting cost",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Bonus\n    Type: timebonus\n    BonusTime: 5m\n    CastingCost:\n      Type: photo\n",
+			err:  true,
+		},
+		{
+			name: "discard cost without amount",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Curse\n    Type: curse\n    CastingCost:\n      Type: discard\n",
+			err:  true,
+		},
+		{
+			name: "photo cost with amount",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Curse\n    Type: curse\n    CastingCost:\n      Type: photo\n      Amount: 2\n",
+			err:  true,
+		},
+		{
+			name: "unknown casting cost",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Curse\n    Type: curse\n    CastingCost:\n      Type: dance\n",
+			err:  true,
+		},
+		{
+			name: "negative count",
+			file: "pack.yaml",
+			pack: "Name: Test\nCards:\n  - Title: Veto\n    Type: veto\n    Count: -1\n",
+			err:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			err := os.WriteFile(path, []byte(test.pack), 0o644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pack, err := LoadPack(path)
+			if test.err {
+				if err == nil {
+					t.Fatalf("loaded pack %v, want an error", pack)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pack.Key != "pack" {
+				t.Errorf("got key %s, want pack", pack.Key)
+			}
+			if len(pack.Cards) != test.cards {
+				t.Errorf("got %d cards, want %d", len(pack.Cards), test.cards)
+			}
+		})
+	}
+}
+
+func TestLoadPacks(t *testing.T) {
+	packs, err := LoadPacks(filepath.Join("..", "packs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := packs[sharedModels.DefaultDeckPack]; !ok {
+		t.Errorf("the pack %s is missing", sharedModels.DefaultDeckPack)
+	}
+
+	// a directory without the default pack
+	dir := t.TempDir()
+	err = os.WriteFile(filepath.Join(dir, "other.yaml"), []byte("Name: Other\nCards:\n  - Title: Veto\n    Type: veto\n"), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = LoadPacks(dir)
+	if !errors.Is(err, ErrInvalidDeckPack) {
+		t.Errorf("got error %v, want %v", err, ErrInvalidDeckPack)
+	}
+}
@@ -0,0 +1,129 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+type batchKey struct{}
+
+// the statement setting that marks the statement which started a batch
+const batchSetting = "events:batch"
+
+// collects the events of db writes so that clients only get them once the writes are committed
+type Batch struct {
+	events []batchedEvent
+}
+
+type batchedEvent struct {
+	lobbyID uint
+	event   sharedModels.LobbyEvent
+}
+
+// the payload gets marshaled right away, so later changes to it don't end up in the event
+func (b *Batch) Add(lobbyID uint, eventType sharedModels.LobbyEventType, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Err(err).Msg("failed marshaling payload of " + string(eventType) + " event")
+		return
+	}
+	b.events = append(b.events, batchedEvent{
+		lobbyID: lobbyID,
+		event: sharedModels.LobbyEvent{
+			Type: eventType,
+			Data: data,
+		},
+	})
+}
+
+// sends the collected events and empties the batch
+func (b *Batch) Publish() {
+	for _, batched := range b.events {
+		publishEvent(batched.lobbyID, batched.event)
+	}
+	b.events = nil
+}
+
+func batchFromContext(ctx context.Context) *Batch {
+	if ctx == nil {
+		return nil
+	}
+	batch, _ := ctx.Value(batchKey{}).(*Batch)
+	return batch
+}
+
+// publishes the event once the writes of db are committed.
+// db handles that aren't part of a batch already committed their writes, so the event gets published right away
+func Queue(db *gorm.DB, lobbyID uint, eventType sharedModels.LobbyEventType, payload interface{}) {
+	batch := batchFromContext(db.Statement.Context)
+	if batch == nil {
+		Publish(lobbyID, eventType, payload)
+		return
+	}
+	batch.Add(lobbyID, eventType, payload)
+}
+
+// runs the function in a transaction and publishes the events of its writes after the commit.
+// nothing gets published if the transaction is rolled back
+func Transaction(db *gorm.DB, fc func(tx *gorm.DB) error) error {
+	// nested transactions get published with the outer one
+	if batchFromContext(db.Statement.Context) != nil {
+		return db.Transaction(fc)
+	}
+	batch := &Batch{}
+	err := db.WithContext(context.WithValue(db.Statement.Context, batchKey{}, batch)).Transaction(fc)
+	if err != nil {
+		return err
+	}
+	batch.Publish()
+	return nil
+}
+
+// makes every create, update and delete collect the events of its hooks and publish them after its own commit
+func RegisterCallbacks(db *gorm.DB) error {
+	err := db.Callback().Create().Before("gorm:begin_transaction").Register("events:begin_batch", beginBatch)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Create().After("gorm:commit_or_rollback_transaction").Register("events:publish_batch", publishBatch)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Update().Before("gorm:begin_transaction").Register("events:begin_batch", beginBatch)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Update().After("gorm:commit_or_rollback_transaction").Register("events:publish_batch", publishBatch)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Delete().Before("gorm:begin_transaction").Register("events:begin_batch", beginBatch)
+	if err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:commit_or_rollback_transaction").Register("events:publish_batch", publishBatch)
+}
+
+// statements outside of Transaction get their own batch
+func beginBatch(db *gorm.DB) {
+	if batchFromContext(db.Statement.Context) != nil {
+		return
+	}
+	batch := &Batch{}
+	db.Statement.Context = context.WithValue(db.Statement.Context, batchKey{}, batch)
+	db.InstanceSet(batchSetting, batch)
+}
+
+func publishBatch(db *gorm.DB) {
+	batch, ok := db.InstanceGet(batchSetting)
+	if !ok || db.Error != nil {
+		return
+	}
+	batch.(*Batch).Publish()
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+// how many events can queue up for a slow client before new ones get dropped
+const subscriberBufferSize = 32
+
+var (
+	mutex       sync.Mutex
+	subscribers = make(map[uint]map[chan sharedModels.LobbyEvent]struct{})
+)
+
+// returns a channel that receives all events of the lobby and a function that has to be called when the subscriber goes away
+func Subscribe(lobbyID uint) (<-chan sharedModels.LobbyEvent, func()) {
+	channel := make(chan sharedModels.LobbyEvent, subscriberBufferSize)
+
+	mutex.Lock()
+	if subscribers[lobbyID] == nil {
+		subscribers[lobbyID] = make(map[chan sharedModels.LobbyEvent]struct{})
+	}
+	subscribers[lobbyID][channel] = struct{}{}
+	mutex.Unlock()
+
+	unsubscribe := func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+		delete(subscribers[lobbyID], channel)
+		if len(subscribers[lobbyID]) == 0 {
+			delete(subscribers, lobbyID)
+		}
+	}
+	return channel, unsubscribe
+}
+
+// sends an event to every subscriber of the lobby. never blocks
+func Publish(lobbyID uint, eventType sharedModels.LobbyEventType, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		log.Err(err).Msg("failed marshaling payload of " + string(eventType) + " event")
+		return
+	}
+	publishEvent(lobbyID, sharedModels.LobbyEvent{
+		Type: eventType,
+		Data: data,
+	})
+}
+
+func publishEvent(lobbyID uint, event sharedModels.LobbyEvent) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	for channel := range subscribers[lobbyID] {
+		select {
+		case channel <- event:
+		default:
+			log.Warn().Msg("dropping " + string(event.Type) + " event for slow subscriber of lobby " + fmt.Sprint(lobbyID))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package geo
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/helpers"
+
+	"github.com/jkulzer/osm"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+
+	"github.com/engelsjk/polygol"
+)
+
+type AreaLevel int
+
+const (
+	// Bezirke in Berlin
+	DistrictLevel AreaLevel = iota
+	// Ortsteile in Berlin
+	SubdistrictLevel
+)
+
+// an administrative area whose polygon got assembled once while loading
+type AdminArea struct {
+	RelationID osm.RelationID
+	Name       string
+	Level      AreaLevel
+	Polygon    orb.MultiPolygon
+	Bound      orb.Bound
+}
+
+func buildAdminAreas(relations map[osm.RelationID]*osm.Relation, level AreaLevel, nodes map[osm.NodeID]*osm.Node, ways map[osm.WayID]*osm.Way) ([]AdminArea, []osm.RelationID) {
+	var areas []AdminArea
+	var unclosedRelations []osm.RelationID
+	for _, relation := range relations {
+		name := relation.Tags.Find("name")
+		if name == "" {
+			log.Warn().Msg("area with ID " + fmt.Sprint(relation.ElementID()) + " has empty name field")
+			continue
+		}
+		multiPolygon, err := RelationToMultiPolygon(*relation, nodes, ways)
+		if errors.Is(err, ErrUnclosedRing) {
+			// the rings that could be closed are still used, the area might just miss a piece
+			log.Warn().Msg("relation " + fmt.Sprint(relation.ID) + " (" + name + ") has rings that couldn't be closed")
+			unclosedRelations = append(unclosedRelations, relation.ID)
+		} else if err != nil {
+			log.Err(err).Msg("failed converting relation " + fmt.Sprint(relation.ID) + " to polygon")
+			continue
+		}
+		if len(multiPolygon) == 0 {
+			continue
+		}
+		areas = append(areas, AdminArea{
+			RelationID: relation.ID,
+			Name:       name,
+			Level:      level,
+			Polygon:    multiPolygon,
+			Bound:      multiPolygon.Bound(),
+		})
+	}
+	return areas, unclosedRelations
+}
+
+// returns the area of the given level that contains the point
+func (d ProcessedData) LocateArea(point orb.Point, level AreaLevel) (AdminArea, bool) {
+	for _, area := range d.AdminAreas[level] {
+		if !area.Bound.Contains(point) {
+			continue
+		}
+		if planar.MultiPolygonContains(area.Polygon, point) {
+			return area, true
+		}
+	}
+	return AdminArea{}, false
+}
+
+// returns the part of the polygon that lies in the game area.
+// the polygon stays as it is if the boundary of the game area couldn't be built
+func (d ProcessedData) ClipToGameArea(polygon orb.Polygon) (orb.MultiPolygon, error) {
+	if d.GameArea == nil {
+		return orb.MultiPolygon{polygon}, nil
+	}
+	if !d.GameAreaBound.Intersects(polygon.Bound()) {
+		return orb.MultiPolygon{}, nil
+	}
+	intersection, err := polygol.Intersection(helpers.G2p(polygon), helpers.G2p(d.GameArea))
+	if err != nil {
+		return nil, err
+	}
+	return helpers.P2g(intersection), nil
+}
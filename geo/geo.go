@@ -5,9 +5,8 @@ import (
 	// "encoding/json"
 	// "errors"
 	"fmt"
+	"math"
 	"os"
-	"slices"
-	"strings"
 
 	"github.com/rs/zerolog/log"
 
@@ -16,8 +15,8 @@ import (
 
 	// "github.com/golang/geo/s2"
 	"github.com/paulmach/orb"
-	"github.com/paulmach/orb/geo"
 	"github.com/paulmach/orb/geojson"
+	"github.com/paulmach/orb/planar"
 	"github.com/paulmach/orb/simplify"
 
 	"github.com/jkulzer/osm"
@@ -25,187 +24,574 @@ import (
 )
 
 type ProcessedData struct {
-	CityBoundary                   *osm.Relation
-	Nodes                          map[osm.NodeID]*osm.Node
-	Ways                           map[osm.WayID]*osm.Way
-	Relations                      map[osm.RelationID]*osm.Relation
-	AllRailRoutes                  map[osm.RelationID]*osm.Relation
-	McDonaldsNodes                 map[osm.NodeID]*osm.Node
-	McDonaldsWays                  map[osm.WayID]*osm.Way
-	IkeaWays                       map[osm.WayID]*osm.Way
-	SpreeLineStrings               []orb.LineString
-	RailwayStations                map[osm.NodeID]*osm.Node
-	Bezirke                        map[osm.RelationID]*osm.Relation
-	Ortsteile                      map[osm.RelationID]*osm.Relation
+	// the profile the data was loaded with
+	Profile      RegionProfile
+	CityBoundary *osm.Relation
+	// the polygon of the city boundary, nil if it couldn't be built
+	GameArea orb.Polygon
+	// the bounding box of the city boundary
+	GameAreaBound orb.Bound
+	Nodes         map[osm.NodeID]*osm.Node
+	Ways          map[osm.WayID]*osm.Way
+	Relations     map[osm.RelationID]*osm.Relation
+	AllRailRoutes map[osm.RelationID]*osm.Relation
+	// the objects of the measuring categories, keyed like in the profile
+	CategoryNodes   map[string]map[osm.NodeID]*osm.Node
+	CategoryWays    map[string]map[osm.WayID]*osm.Way
+	LineFeatures    map[string][]orb.LineString
+	RailwayStations map[osm.NodeID]*osm.Node
+	Districts       map[osm.RelationID]*osm.Relation
+	Subdistricts    map[osm.RelationID]*osm.Relation
+	// the assembled polygons of the districts and subdistricts
+	AdminAreas map[AreaLevel][]AdminArea
+	// area relations whose ways couldn't all be stitched into closed rings
+	UnclosedRelations []osm.RelationID
+	// spatial indexes, built once when loading
+	RailwayStationIndex *PointIndex
+	CategoryIndexes     map[string]*PointIndex
+	// every vertex of the rail routes, pointing to the route relation
+	RailRouteIndex *PointIndex
+	// addresses and named streets for the reverse geocoder
+	AddressIndex *PointIndex
+	StreetIndex  *PointIndex
+	// the marshalled feature collection of the game area border
 	MapMarshalledFeatureCollection []byte
 }
 
-func ProcessData() ProcessedData {
+// scans the whole PBF file of the region. this takes minutes, use LoadData to go through the snapshot cache
+func ProcessData(profile RegionProfile) ProcessedData {
+	return scanPBF(profile).result()
+}
+
+// only keeps the nodes and ways that are referenced by the needed relations or matched by their tags.
+// because relations come last in PBF files, every element type gets its own pass, starting with the relations
+func scanPBF(profile RegionProfile) *dataCollector {
+	log.Info().Msg("starting processing of OSM data for " + profile.Name + ". this is blocking")
+
+	collector := collectInPasses(profile, func(elementType osm.Type, handleObject func(osm.Object)) {
+		scanPBFType(profile, elementType, handleObject)
+	})
+
+	log.Info().Msg("finished processing of OSM data")
+
+	return collector
+}
+
+// runs the passes of scanPBF, scan has to call handleObject for every object of the element type
+func collectInPasses(profile RegionProfile, scan func(elementType osm.Type, handleObject func(osm.Object))) *dataCollector {
+	collector := newDataCollector(profile)
+
+	scan(osm.TypeRelation, collector.add)
+	collector.relations = collector.neededRelations()
+	collector.wantedWays = collector.wantedWayIDs(collector.relations)
+	log.Info().Msg(fmt.Sprint("kept ", len(collector.relations), " relations referencing ", len(collector.wantedWays), " ways"))
+
+	scan(osm.TypeWay, collector.add)
+	collector.wantedNodes = collector.wantedNodeIDs(collector.relations, collector.ways)
+	log.Info().Msg(fmt.Sprint("kept ", len(collector.ways), " ways referencing ", len(collector.wantedNodes), " nodes"))
+
+	scan(osm.TypeNode, collector.add)
+	log.Info().Msg(fmt.Sprint("kept ", len(collector.nodes), " nodes"))
 
-	osmFile, err := os.Open("./berlin-latest.osm.pbf")
+	return collector
+}
+
+func scanPBFType(profile RegionProfile, elementType osm.Type, handleObject func(osm.Object)) {
+	osmFile, err := os.Open(profile.PBFPath)
 	if err != nil {
-		log.Err(err)
+		log.Fatal().Err(err).Msg("failed opening " + profile.PBFPath)
 	}
+	defer osmFile.Close()
 	scanner := osmpbf.New(context.Background(), osmFile, 4)
+	defer scanner.Close()
+
+	scanner.SkipNodes = elementType != osm.TypeNode
+	scanner.SkipWays = elementType != osm.TypeWay
+	scanner.SkipRelations = elementType != osm.TypeRelation
 
-	log.Info().Msg("starting processing of OSM data. this is blocking")
+	for scanner.Scan() {
+		handleObject(scanner.Object())
+	}
+	if scanner.Err() != nil {
+		log.Err(scanner.Err()).Msg("failed scanning " + profile.PBFPath)
+	}
+}
 
-	bezirke := make(map[osm.RelationID]*osm.Relation)
-	ortsteile := make(map[osm.RelationID]*osm.Relation)
+// sorts the OSM objects of a region into the maps of ProcessedData
+type dataCollector struct {
+	profile RegionProfile
 
-	nodes := make(map[osm.NodeID]*osm.Node)
-	ways := make(map[osm.WayID]*osm.Way)
-	relations := make(map[osm.RelationID]*osm.Relation)
+	districts    map[osm.RelationID]*osm.Relation
+	subdistricts map[osm.RelationID]*osm.Relation
+
+	nodes     map[osm.NodeID]*osm.Node
+	ways      map[osm.WayID]*osm.Way
+	relations map[osm.RelationID]*osm.Relation
 
 	// hiding point validity
-	railwayStations := make(map[osm.NodeID]*osm.Node)
+	railwayStations map[osm.NodeID]*osm.Node
 
-	mcDonaldsNodes := make(map[osm.NodeID]*osm.Node)
-	mcDonaldsWays := make(map[osm.WayID]*osm.Way)
+	categoryNodes map[string]map[osm.NodeID]*osm.Node
+	categoryWays  map[string]map[osm.WayID]*osm.Way
 
-	ikeaWays := make(map[osm.WayID]*osm.Way)
+	// reverse geocoding
+	addressNodes map[osm.NodeID]*osm.Node
+	addressWays  map[osm.WayID]*osm.Way
+	streetWays   map[osm.WayID]*osm.Way
 
-	subwayLines := make(map[osm.RelationID]*osm.Relation)
-	sbahnLines := make(map[osm.RelationID]*osm.Relation)
-	allRailRoutes := make(map[osm.RelationID]*osm.Relation)
+	subwayLines   map[osm.RelationID]*osm.Relation
+	sbahnLines    map[osm.RelationID]*osm.Relation
+	allRailRoutes map[osm.RelationID]*osm.Relation
 
-	var spreeRelation *osm.Relation
-	var spreeLineStrings []orb.LineString
+	lineFeatureRelations map[string][]*osm.Relation
+	lineFeatureWays      map[string]map[osm.WayID]*osm.Way
 
-	var berlinBoundary *osm.Relation
+	cityBoundary *osm.Relation
 
-	for scanner.Scan() {
-		// Get the next OSM object
-		obj := scanner.Object()
-
-		switch v := obj.(type) {
-		case *osm.Node:
-			nodes[v.ID] = v
-			if v.Tags.Find("railway") == "station" || v.Tags.Find("railway") == "halt" {
-				if v.Tags.Find("usage ") != "tourism" {
-					railwayStations[v.ID] = v
-				}
-			}
-			if v.Tags.Find("brand") == "McDonald's" {
-				mcDonaldsNodes[v.ID] = v
-			}
-		case *osm.Way:
-			ways[v.ID] = v
-			brandTag := v.Tags.Find("brand")
-			if brandTag == "McDonald's" {
-				mcDonaldsWays[v.ID] = v
-			}
-			if brandTag == "IKEA" && !strings.Contains(v.Tags.Find("name"), "Planning studio") {
-				ikeaWays[v.ID] = v
-			}
-		case *osm.Relation:
-			relations[v.ID] = v
-			if v.Tags.Find("admin_level") == "9" && v.Tags.Find("name:prefix") == "Bezirk" {
-				bezirke[v.ID] = v
+	// when set, only these ways and nodes are kept in addition to the ones matched by their tags
+	wantedWays  map[osm.WayID]struct{}
+	wantedNodes map[osm.NodeID]struct{}
+}
+
+func newDataCollector(profile RegionProfile) *dataCollector {
+	c := &dataCollector{
+		profile:              profile,
+		districts:            make(map[osm.RelationID]*osm.Relation),
+		subdistricts:         make(map[osm.RelationID]*osm.Relation),
+		nodes:                make(map[osm.NodeID]*osm.Node),
+		ways:                 make(map[osm.WayID]*osm.Way),
+		relations:            make(map[osm.RelationID]*osm.Relation),
+		railwayStations:      make(map[osm.NodeID]*osm.Node),
+		categoryNodes:        make(map[string]map[osm.NodeID]*osm.Node),
+		categoryWays:         make(map[string]map[osm.WayID]*osm.Way),
+		addressNodes:         make(map[osm.NodeID]*osm.Node),
+		addressWays:          make(map[osm.WayID]*osm.Way),
+		streetWays:           make(map[osm.WayID]*osm.Way),
+		subwayLines:          make(map[osm.RelationID]*osm.Relation),
+		sbahnLines:           make(map[osm.RelationID]*osm.Relation),
+		allRailRoutes:        make(map[osm.RelationID]*osm.Relation),
+		lineFeatureRelations: make(map[string][]*osm.Relation),
+		lineFeatureWays:      make(map[string]map[osm.WayID]*osm.Way),
+	}
+	for categoryKey := range profile.MeasuringCategories {
+		c.categoryNodes[categoryKey] = make(map[osm.NodeID]*osm.Node)
+		c.categoryWays[categoryKey] = make(map[osm.WayID]*osm.Way)
+	}
+	for lineKey := range profile.LineFeatures {
+		c.lineFeatureWays[lineKey] = make(map[osm.WayID]*osm.Way)
+	}
+	return c
+}
+
+func (c *dataCollector) add(obj osm.Object) {
+	switch v := obj.(type) {
+	case *osm.Node:
+		isMatched := false
+		if v.Tags.Find("railway") == "station" || v.Tags.Find("railway") == "halt" {
+			if v.Tags.Find("usage ") != "tourism" {
+				c.railwayStations[v.ID] = v
+				isMatched = true
 			}
-			if v.Tags.Find("admin_level") == "10" {
-				ortsteile[v.ID] = v
+		}
+		for categoryKey, category := range c.profile.MeasuringCategories {
+			if !category.WaysOnly && category.Matches(v.Tags) {
+				c.categoryNodes[categoryKey][v.ID] = v
+				isMatched = true
 			}
-			if v.Tags.Find("admin_level") == "4" && v.Tags.Find("de:amtlicher_gemeindeschluessel") == "11000000" {
-				berlinBoundary = v
+		}
+		if hasAddress(v.Tags) {
+			c.addressNodes[v.ID] = v
+			isMatched = true
+		}
+		if _, isWanted := c.wantedNodes[v.ID]; isMatched || isWanted || c.wantedNodes == nil {
+			c.nodes[v.ID] = v
+		}
+	case *osm.Way:
+		isMatched := false
+		for categoryKey, category := range c.profile.MeasuringCategories {
+			if category.Matches(v.Tags) {
+				c.categoryWays[categoryKey][v.ID] = v
+				isMatched = true
 			}
-			routeTag := v.Tags.Find("route")
-			if routeTag == "subway" {
-				subwayLines[v.ID] = v
-				allRailRoutes[v.ID] = v
-			} else if routeTag == "light_rail" {
-				sbahnLines[v.ID] = v
-				allRailRoutes[v.ID] = v
-			} else if v.Tags.Find("service") == "regional" {
-				allRailRoutes[v.ID] = v
+		}
+		if hasAddress(v.Tags) {
+			c.addressWays[v.ID] = v
+			isMatched = true
+		}
+		if isNamedStreet(v.Tags) {
+			c.streetWays[v.ID] = v
+			isMatched = true
+		}
+		for lineKey, lineFeature := range c.profile.LineFeatures {
+			if lineFeature.MatchesWay(v.Tags) {
+				c.lineFeatureWays[lineKey][v.ID] = v
+				isMatched = true
 			}
-			if v.Tags.Find("name") == "Spree" {
-				spreeRelation = v
+		}
+		if _, isWanted := c.wantedWays[v.ID]; isMatched || isWanted || c.wantedWays == nil {
+			c.ways[v.ID] = v
+		}
+	case *osm.Relation:
+		c.relations[v.ID] = v
+		if c.profile.Districts.Matches(v.Tags) {
+			c.districts[v.ID] = v
+		}
+		if c.profile.Subdistricts.Matches(v.Tags) {
+			c.subdistricts[v.ID] = v
+		}
+		if c.profile.Boundary.Matches(v.Tags) {
+			c.cityBoundary = v
+		}
+		routeTag := v.Tags.Find("route")
+		if routeTag == "subway" {
+			c.subwayLines[v.ID] = v
+			c.allRailRoutes[v.ID] = v
+		} else if routeTag == "light_rail" {
+			c.sbahnLines[v.ID] = v
+			c.allRailRoutes[v.ID] = v
+		} else if v.Tags.Find("service") == "regional" {
+			c.allRailRoutes[v.ID] = v
+		}
+		for lineKey, lineFeature := range c.profile.LineFeatures {
+			if lineFeature.MatchesRelation(v.Tags) {
+				c.lineFeatureRelations[lineKey] = append(c.lineFeatureRelations[lineKey], v)
 			}
-		default:
-			// Handle other OSM object types if needed
 		}
+	default:
+		// Handle other OSM object types if needed
 	}
+}
 
-	fc := geojson.NewFeatureCollection()
+func (c *dataCollector) result() ProcessedData {
+	profile := c.profile
+	sharedModels.SetWorldBounds(profile.Bounds.Left, profile.Bounds.Right, profile.Bounds.Top, profile.Bounds.Bottom)
 
-	var berlinBoundaryLineStrings []orb.LineString
+	fc := geojson.NewFeatureCollection()
 
-	for _, member := range spreeRelation.Members {
-		if member.Type == "way" {
-			wayID, err := member.ElementID().WayID()
-			if err != nil {
-				log.Err(err).Msg("")
-				continue
+	lineFeatures := make(map[string][]orb.LineString)
+	var boundaryLineStrings []orb.LineString
+
+	for lineKey := range profile.LineFeatures {
+		// a way can be part of a matched relation and be matched itself
+		lineWays := make(map[osm.WayID]*osm.Way)
+		for _, relation := range c.lineFeatureRelations[lineKey] {
+			for _, member := range relation.Members {
+				if member.Type == "way" {
+					wayID, err := member.ElementID().WayID()
+					if err != nil {
+						log.Err(err).Msg("")
+						continue
+					}
+					way := c.ways[wayID]
+					if way != nil {
+						lineWays[wayID] = way
+					}
+				}
 			}
-			way := ways[wayID]
-			if way != nil {
-				lineString := LineStringFromWay(way, nodes)
-				spreeLineStrings = append(spreeLineStrings, lineString)
+		}
+		for wayID, way := range c.lineFeatureWays[lineKey] {
+			lineWays[wayID] = way
+		}
+		for _, way := range lineWays {
+			lineString := LineStringFromWay(way, c.nodes)
+			if len(lineString) >= 2 {
+				lineFeatures[lineKey] = append(lineFeatures[lineKey], lineString)
 			}
 		}
 	}
 
-	for _, member := range berlinBoundary.Members {
+	if c.cityBoundary == nil {
+		log.Fatal().Msg("couldn't find the boundary relation of " + profile.Name + " in " + profile.PBFPath)
+	}
+
+	for _, member := range c.cityBoundary.Members {
 		if member.Type == "way" {
 			wayID, err := member.ElementID().WayID()
 			if err != nil {
 				log.Err(err).Msg("")
 				continue
 			}
-			way := ways[wayID]
-			lineString := LineStringFromWay(way, nodes)
-			berlinBoundaryLineStrings = append(berlinBoundaryLineStrings, lineString)
+			way := c.ways[wayID]
+			lineString := LineStringFromWay(way, c.nodes)
+			boundaryLineStrings = append(boundaryLineStrings, lineString)
 		}
 	}
-	berlinBoundaryRing, err := RingFromLineStrings(berlinBoundaryLineStrings)
-	berlinBoundaryRing.Reverse()
+	// the world bounds are used if the boundary can't be built
+	gameAreaBound := orb.Bound{
+		Min: orb.Point{profile.Bounds.Left, profile.Bounds.Bottom},
+		Max: orb.Point{profile.Bounds.Right, profile.Bounds.Top},
+	}
+	var gameArea orb.Polygon
+	boundaryRing, err := RingFromLineStrings(boundaryLineStrings)
+	boundaryRing.Reverse()
 	if err != nil {
-		berlinBoundaryPolygon := orb.Polygon([]orb.Ring{berlinBoundaryRing})
-		// simplify.DouglasPeucker(0.001).Polygon(berlinBoundaryPolygon)
-		berlinBoundaryFeature := geojson.NewFeature(berlinBoundaryPolygon)
-		berlinBoundaryFeature.Properties["category"] = "game_area_border"
-		fc.Append(berlinBoundaryFeature)
+		log.Err(err).Msg("failed building the boundary ring of " + profile.Name)
+	} else {
+		gameAreaBound = boundaryRing.Bound()
+		boundaryPolygon := orb.Polygon([]orb.Ring{boundaryRing})
+		gameArea = boundaryPolygon
+		// simplify.DouglasPeucker(0.001).Polygon(boundaryPolygon)
+		boundaryFeature := geojson.NewFeature(boundaryPolygon)
+		boundaryFeature.Properties["category"] = "game_area_border"
+		fc.Append(boundaryFeature)
 	}
 	marshalledFC, _ := fc.MarshalJSON()
 	// writeAndMarshallFC(fc)
 
-	log.Info().Msg("finished processing of OSM data")
+	var stationPoints []IndexedPoint
+	for _, station := range c.railwayStations {
+		stationPoints = append(stationPoints, IndexedPoint{
+			Location:  helpers.NodeToPoint(*station),
+			ElementID: station.ElementID(),
+			Name:      station.Tags.Find("name"),
+		})
+	}
+
+	categoryIndexes := make(map[string]*PointIndex)
+	for categoryKey := range profile.MeasuringCategories {
+		var categoryPoints []IndexedPoint
+		for _, node := range c.categoryNodes[categoryKey] {
+			categoryPoints = append(categoryPoints, IndexedPoint{
+				Location:  node.Point(),
+				ElementID: node.ElementID(),
+				Name:      node.Tags.Find("name"),
+			})
+		}
+		for _, way := range c.categoryWays[categoryKey] {
+			// ways whose nodes weren't collected would end up at 0, 0
+			lineString := LineStringFromWay(way, c.nodes)
+			if len(lineString) == 0 {
+				continue
+			}
+			categoryPoints = append(categoryPoints, IndexedPoint{
+				Location:  lineString.Bound().Center(),
+				ElementID: way.ElementID(),
+				Name:      way.Tags.Find("name"),
+			})
+		}
+		categoryIndexes[categoryKey] = NewPointIndex(categoryPoints)
+	}
+
+	var addressPoints []IndexedPoint
+	for _, node := range c.addressNodes {
+		addressPoints = append(addressPoints, IndexedPoint{
+			Location:  node.Point(),
+			ElementID: node.ElementID(),
+			Name:      addressString(node.Tags),
+		})
+	}
+	for _, way := range c.addressWays {
+		lineString := LineStringFromWay(way, c.nodes)
+		if len(lineString) == 0 {
+			continue
+		}
+		addressPoints = append(addressPoints, IndexedPoint{
+			Location:  lineString.Bound().Center(),
+			ElementID: way.ElementID(),
+			Name:      addressString(way.Tags),
+		})
+	}
+
+	var streetPoints []IndexedPoint
+	for _, way := range c.streetWays {
+		for _, streetPoint := range LineStringFromWay(way, c.nodes) {
+			streetPoints = append(streetPoints, IndexedPoint{
+				Location:  streetPoint,
+				ElementID: way.ElementID(),
+				Name:      way.Tags.Find("name"),
+			})
+		}
+	}
+
+	var routePoints []IndexedPoint
+	for _, route := range c.allRailRoutes {
+		for _, member := range route.Members {
+			if member.Type != osm.TypeWay {
+				continue
+			}
+			memberWay := c.ways[osm.WayID(member.Ref)]
+			for _, routePoint := range LineStringFromWay(memberWay, c.nodes) {
+				routePoints = append(routePoints, IndexedPoint{
+					Location:  routePoint,
+					ElementID: route.ElementID(),
+					Name:      route.Tags.Find("ref"),
+				})
+			}
+		}
+	}
+
+	adminAreas := make(map[AreaLevel][]AdminArea)
+	var unclosedRelations []osm.RelationID
+	for level, relations := range map[AreaLevel]map[osm.RelationID]*osm.Relation{
+		DistrictLevel:    c.districts,
+		SubdistrictLevel: c.subdistricts,
+	} {
+		areas, unclosed := buildAdminAreas(relations, level, c.nodes, c.ways)
+		adminAreas[level] = areas
+		unclosedRelations = append(unclosedRelations, unclosed...)
+	}
+	if len(unclosedRelations) > 0 {
+		log.Warn().Msg(fmt.Sprint(len(unclosedRelations), " area relations couldn't be closed completely: ", unclosedRelations))
+	}
 
 	return ProcessedData{
-		CityBoundary:                   berlinBoundary,
-		Bezirke:                        bezirke,
-		Ortsteile:                      ortsteile,
-		Nodes:                          nodes,
-		Ways:                           ways,
-		Relations:                      relations,
-		McDonaldsNodes:                 mcDonaldsNodes,
-		McDonaldsWays:                  mcDonaldsWays,
-		IkeaWays:                       ikeaWays,
-		SpreeLineStrings:               spreeLineStrings,
-		AllRailRoutes:                  allRailRoutes,
-		RailwayStations:                railwayStations,
+		Profile:                        profile,
+		CityBoundary:                   c.cityBoundary,
+		GameArea:                       gameArea,
+		GameAreaBound:                  gameAreaBound,
+		Districts:                      c.districts,
+		Subdistricts:                   c.subdistricts,
+		Nodes:                          c.nodes,
+		Ways:                           c.ways,
+		Relations:                      c.relations,
+		CategoryNodes:                  c.categoryNodes,
+		CategoryWays:                   c.categoryWays,
+		LineFeatures:                   lineFeatures,
+		AllRailRoutes:                  c.allRailRoutes,
+		RailwayStations:                c.railwayStations,
+		AdminAreas:                     adminAreas,
+		UnclosedRelations:              unclosedRelations,
+		RailwayStationIndex:            NewPointIndex(stationPoints),
+		CategoryIndexes:                categoryIndexes,
+		RailRouteIndex:                 NewPointIndex(routePoints),
+		AddressIndex:                   NewPointIndex(addressPoints),
+		StreetIndex:                    NewPointIndex(streetPoints),
 		MapMarshalledFeatureCollection: marshalledFC,
 	}
 }
 
-func PointIsValidZoneCenter(hiderPoint orb.Point, data ProcessedData) bool {
-	for _, railwayStation := range data.RailwayStations {
-		railwayStationPoint := helpers.NodeToPoint(*railwayStation)
-		distanceFromRailStation := geo.DistanceHaversine(hiderPoint, railwayStationPoint)
-		if distanceFromRailStation <= sharedModels.HidingZoneRadius {
-			return true
+// the relations that are used after loading, everything else can be dropped
+func (c *dataCollector) neededRelations() map[osm.RelationID]*osm.Relation {
+	relations := make(map[osm.RelationID]*osm.Relation)
+	for relationID, relation := range c.districts {
+		relations[relationID] = relation
+	}
+	for relationID, relation := range c.subdistricts {
+		relations[relationID] = relation
+	}
+	for relationID, relation := range c.allRailRoutes {
+		relations[relationID] = relation
+	}
+	for _, lineRelations := range c.lineFeatureRelations {
+		for _, relation := range lineRelations {
+			relations[relation.ID] = relation
+		}
+	}
+	if c.cityBoundary != nil {
+		relations[c.cityBoundary.ID] = c.cityBoundary
+	}
+	return relations
+}
+
+func (c *dataCollector) wantedWayIDs(relations map[osm.RelationID]*osm.Relation) map[osm.WayID]struct{} {
+	wayIDs := make(map[osm.WayID]struct{})
+	for _, relation := range relations {
+		for _, member := range relation.Members {
+			if member.Type == osm.TypeWay {
+				wayIDs[osm.WayID(member.Ref)] = struct{}{}
+			}
+		}
+	}
+	return wayIDs
+}
+
+func (c *dataCollector) wantedNodeIDs(relations map[osm.RelationID]*osm.Relation, ways map[osm.WayID]*osm.Way) map[osm.NodeID]struct{} {
+	nodeIDs := make(map[osm.NodeID]struct{})
+	for _, way := range ways {
+		for _, wayNode := range way.Nodes {
+			nodeIDs[wayNode.ID] = struct{}{}
+		}
+	}
+	for _, relation := range relations {
+		for _, member := range relation.Members {
+			if member.Type == osm.TypeNode {
+				nodeIDs[osm.NodeID(member.Ref)] = struct{}{}
+			}
+		}
+	}
+	return nodeIDs
+}
+
+func (c *dataCollector) neededWays(relations map[osm.RelationID]*osm.Relation) map[osm.WayID]*osm.Way {
+	ways := make(map[osm.WayID]*osm.Way)
+	for _, relation := range relations {
+		for _, member := range relation.Members {
+			if member.Type != osm.TypeWay {
+				continue
+			}
+			wayID := osm.WayID(member.Ref)
+			if way := c.ways[wayID]; way != nil {
+				ways[wayID] = way
+			}
+		}
+	}
+	for _, categoryWays := range c.categoryWays {
+		for wayID, way := range categoryWays {
+			ways[wayID] = way
+		}
+	}
+	for wayID, way := range c.addressWays {
+		ways[wayID] = way
+	}
+	for wayID, way := range c.streetWays {
+		ways[wayID] = way
+	}
+	for _, lineWays := range c.lineFeatureWays {
+		for wayID, way := range lineWays {
+			ways[wayID] = way
+		}
+	}
+	return ways
+}
+
+func (c *dataCollector) neededNodes(relations map[osm.RelationID]*osm.Relation, ways map[osm.WayID]*osm.Way) map[osm.NodeID]*osm.Node {
+	nodes := make(map[osm.NodeID]*osm.Node)
+	addNode := func(nodeID osm.NodeID) {
+		if node := c.nodes[nodeID]; node != nil {
+			nodes[nodeID] = node
 		}
 	}
-	return false
+	for _, way := range ways {
+		for _, wayNode := range way.Nodes {
+			addNode(wayNode.ID)
+		}
+	}
+	for _, relation := range relations {
+		for _, member := range relation.Members {
+			if member.Type == osm.TypeNode {
+				addNode(osm.NodeID(member.Ref))
+			}
+		}
+	}
+	for nodeID := range c.railwayStations {
+		addNode(nodeID)
+	}
+	for _, categoryNodes := range c.categoryNodes {
+		for nodeID := range categoryNodes {
+			addNode(nodeID)
+		}
+	}
+	for nodeID := range c.addressNodes {
+		addNode(nodeID)
+	}
+	return nodes
+}
+
+func PointIsValidZoneCenter(hiderPoint orb.Point, hidingZoneRadius float64, data ProcessedData) bool {
+	_, distanceFromRailStation, found := data.RailwayStationIndex.Nearest(hiderPoint)
+	return found && distanceFromRailStation <= hidingZoneRadius
 }
 
 func LineStringFromWay(way *osm.Way, nodes map[osm.NodeID]*osm.Node) orb.LineString {
 	var lineString orb.LineString
 	if way != nil {
 		for _, wayNode := range way.Nodes {
-			point := nodes[wayNode.ID].Point()
-			lineString = append(lineString, point)
+			node := nodes[wayNode.ID]
+			// nodes outside of the extract are missing
+			if node == nil {
+				continue
+			}
+			lineString = append(lineString, node.Point())
 		}
 	}
 	return lineString
@@ -225,31 +611,19 @@ func addToFeatureCollection(category string, fc *geojson.FeatureCollection, coll
 	}
 }
 
+// stitches the line strings into rings and returns the largest one, e.g. the border of the city
 func RingFromLineStrings(lineStrings []orb.LineString) (orb.Ring, error) {
-	var theRing orb.Ring
-	for _, ls := range lineStrings {
-		if len(theRing) > 0 {
-			if theRing[len(theRing)-1] != ls[0] {
-				if theRing[len(theRing)-1] == ls[len(ls)-1] {
-					ls.Reverse()
-				} else {
-					continue
-				}
-			}
-		}
-		for _, point := range ls {
-			theRing = append(theRing, point)
-		}
+	rings, _ := stitchRings(lineStrings)
+	if len(rings) == 0 {
+		return orb.Ring{}, ErrUnclosedRing
 	}
-
-	theRing = append(theRing, theRing[0])
-	return theRing, nil
-}
-
-func appendLSToRing(lineString orb.LineString, ring *orb.Ring) {
-	for _, point := range lineString {
-		*ring = append(*ring, point)
+	largestRing := rings[0]
+	for _, ring := range rings[1:] {
+		if math.Abs(planar.Area(ring)) > math.Abs(planar.Area(largestRing)) {
+			largestRing = ring
+		}
 	}
+	return largestRing, nil
 }
 
 func writeAndMarshallFC(fc *geojson.FeatureCollection) {
@@ -264,69 +638,3 @@ func writeAndMarshallFC(fc *geojson.FeatureCollection) {
 		log.Err(err).Msg("")
 	}
 }
-
-func RelationToMultiPolygon(relationToConvert osm.Relation, nodes map[osm.NodeID]*osm.Node, ways map[osm.WayID]*osm.Way) (orb.MultiPolygon, error) {
-	var lineStrings []orb.LineString
-	for _, member := range relationToConvert.Members {
-		if member.Type == "way" {
-			wayID, err := member.ElementID().WayID()
-			if err != nil {
-				return orb.MultiPolygon{}, err
-			}
-			way := ways[wayID]
-			if way == nil {
-				continue
-			}
-			lineStrings = append(lineStrings, LineStringFromWay(way, nodes))
-		}
-	}
-
-	multiPolygon := lineStringsToMultiPolygon(lineStrings)
-
-	return multiPolygon, nil
-}
-
-func lineStringsToMultiPolygon(lineStrings []orb.LineString) orb.MultiPolygon {
-	var multiPolygon orb.MultiPolygon
-	var polygon orb.Polygon
-
-	firstLineString := lineStrings[0]
-
-	var initialRing orb.Ring
-	initialRing = append(initialRing, firstLineString[0])
-
-	polygon = append(polygon, initialRing)
-
-	lineStrings = slices.Delete(lineStrings, 0, 0)
-
-	for len(lineStrings) > 0 {
-		foundMatch := false
-	forLoop:
-		for lsIndex, ls := range lineStrings {
-			lastPoint := polygon[0][len(polygon[0])-1]
-			switch lastPoint {
-			case ls[0]:
-				appendLSToRing(ls, &polygon[0])
-				lineStrings = slices.Delete(lineStrings, lsIndex, lsIndex+1)
-				foundMatch = true
-				break forLoop
-			case ls[len(ls)-1]:
-				ls.Reverse()
-				appendLSToRing(ls, &polygon[0])
-				lineStrings = slices.Delete(lineStrings, lsIndex, lsIndex+1)
-				foundMatch = true
-				break forLoop
-			}
-		}
-		if !foundMatch {
-			break
-		}
-	}
-	var additionalPolygons orb.MultiPolygon
-	if len(lineStrings) > 0 {
-		additionalPolygons = lineStringsToMultiPolygon(lineStrings)
-	}
-	multiPolygon = append(multiPolygon, additionalPolygons...)
-	multiPolygon = append(multiPolygon, polygon)
-	return multiPolygon
-}
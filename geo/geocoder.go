@@ -0,0 +1,95 @@
+package geo
+
+import (
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/jkulzer/osm"
+
+	"github.com/paulmach/orb"
+)
+
+var ErrNoAddressFound error = errors.New("No address found near the location")
+
+// addresses further away than this aren't used, the closest street gets used instead
+const maxAddressDistance = 100.0
+
+// locations further away than this from every street only get the area names
+const maxStreetDistance = 300.0
+
+// the highway types that count as streets when no address is close by
+var streetHighwayTypes = []string{
+	"motorway",
+	"trunk",
+	"primary",
+	"secondary",
+	"tertiary",
+	"unclassified",
+	"residential",
+	"living_street",
+	"pedestrian",
+	"service",
+}
+
+// turns a location into an address that can be shown to the players
+type ReverseGeocoder interface {
+	ReverseGeocode(point orb.Point) (string, error)
+}
+
+// reverse geocodes from the OSM data of the region, without any network access
+type LocalGeocoder struct {
+	data ProcessedData
+}
+
+func NewLocalGeocoder(data ProcessedData) *LocalGeocoder {
+	return &LocalGeocoder{
+		data: data,
+	}
+}
+
+// returns "street housenumber, Ortsteil, Bezirk". parts that aren't known for the location are left out
+func (g *LocalGeocoder) ReverseGeocode(point orb.Point) (string, error) {
+	var parts []string
+
+	address, addressDistance, foundAddress := g.data.AddressIndex.Nearest(point)
+	if foundAddress && addressDistance <= maxAddressDistance {
+		parts = append(parts, address.Name)
+	} else {
+		street, streetDistance, foundStreet := g.data.StreetIndex.Nearest(point)
+		if foundStreet && streetDistance <= maxStreetDistance {
+			parts = append(parts, street.Name)
+		}
+	}
+
+	if subdistrict, found := g.data.LocateArea(point, SubdistrictLevel); found {
+		parts = append(parts, subdistrict.Name)
+	}
+	if district, found := g.data.LocateArea(point, DistrictLevel); found {
+		parts = append(parts, district.Name)
+	}
+
+	if len(parts) == 0 {
+		return "", ErrNoAddressFound
+	}
+	return strings.Join(parts, ", "), nil
+}
+
+func hasAddress(tags osm.Tags) bool {
+	if !tags.HasTag("addr:housenumber") {
+		return false
+	}
+	return tags.HasTag("addr:street") || tags.HasTag("addr:place")
+}
+
+func isNamedStreet(tags osm.Tags) bool {
+	return tags.Find("name") != "" && slices.Contains(streetHighwayTypes, tags.Find("highway"))
+}
+
+func addressString(tags osm.Tags) string {
+	street := tags.Find("addr:street")
+	if street == "" {
+		street = tags.Find("addr:place")
+	}
+	return street + " " + tags.Find("addr:housenumber")
+}
@@ -0,0 +1,109 @@
+package geo
+
+import (
+	"math"
+
+	"github.com/jkulzer/osm"
+
+	"github.com/paulmach/orb"
+	orbGeo "github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/quadtree"
+)
+
+// the radius the nearest neighbour search starts with, it gets doubled until something is found
+const initialSearchRadius = 250.0
+
+type IndexedPoint struct {
+	Location orb.Point
+	// the OSM element the point stands for, e.g. a station node or the route relation a rail track belongs to
+	ElementID osm.ElementID
+	Name      string
+}
+
+func (p IndexedPoint) Point() orb.Point {
+	return p.Location
+}
+
+// answers nearest neighbour and radius queries on a fixed set of points in meters
+type PointIndex struct {
+	tree   *quadtree.Quadtree
+	points []IndexedPoint
+}
+
+func NewPointIndex(points []IndexedPoint) *PointIndex {
+	bound := orb.Bound{Min: orb.Point{-180, -90}, Max: orb.Point{180, 90}}
+	if len(points) > 0 {
+		bound = orb.MultiPoint{points[0].Location}.Bound()
+		for _, point := range points {
+			bound = bound.Extend(point.Location)
+		}
+	}
+
+	index := &PointIndex{
+		tree:   quadtree.New(bound),
+		points: points,
+	}
+	for _, point := range points {
+		index.tree.Add(point)
+	}
+	return index
+}
+
+func (i *PointIndex) Len() int {
+	return len(i.points)
+}
+
+func (i *PointIndex) Points() []IndexedPoint {
+	return i.points
+}
+
+// returns all points that are at most radius meters away from the center
+func (i *PointIndex) WithinRadius(center orb.Point, radius float64) []IndexedPoint {
+	var result []IndexedPoint
+	if i == nil || len(i.points) == 0 {
+		return result
+	}
+	candidates := i.tree.InBound(nil, orbGeo.NewBoundAroundPoint(center, radius))
+	for _, candidate := range candidates {
+		point := candidate.(IndexedPoint)
+		if orbGeo.DistanceHaversine(center, point.Location) <= radius {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+// returns the closest point to the center and its distance in meters
+func (i *PointIndex) Nearest(center orb.Point) (IndexedPoint, float64, bool) {
+	if i == nil || len(i.points) == 0 {
+		return IndexedPoint{}, math.Inf(1), false
+	}
+
+	// no point can be further away than the furthest corner of the index
+	bound := i.tree.Bound()
+	maxRadius := 0.0
+	for _, corner := range []orb.Point{bound.Min, bound.Max, bound.LeftTop(), bound.RightBottom()} {
+		maxRadius = math.Max(maxRadius, orbGeo.DistanceHaversine(center, corner))
+	}
+
+	for radius := initialSearchRadius; ; radius *= 2 {
+		if radius > maxRadius {
+			radius = maxRadius
+		}
+		nearestDistance := math.Inf(1)
+		var nearestPoint IndexedPoint
+		for _, point := range i.WithinRadius(center, radius) {
+			distance := orbGeo.DistanceHaversine(center, point.Location)
+			if distance < nearestDistance {
+				nearestDistance = distance
+				nearestPoint = point
+			}
+		}
+		if !math.IsInf(nearestDistance, 1) {
+			return nearestPoint, nearestDistance, true
+		}
+		if radius >= maxRadius {
+			return IndexedPoint{}, math.Inf(1), false
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jkulzer/osm"
+
+	"github.com/paulmach/orb"
+)
+
+func indexedPoints(points ...orb.Point) []IndexedPoint {
+	var indexedPoints []IndexedPoint
+	for index, point := range points {
+		indexedPoints = append(indexedPoints, IndexedPoint{
+			Location:  point,
+			ElementID: osm.NodeID(index + 1).ElementID(0),
+		})
+	}
+	return indexedPoints
+}
+
+func TestNearest(t *testing.T) {
+	tests := []struct {
+		name   string
+		index  *PointIndex
+		center orb.Point
+		// index of the expected point, -1 if nothing should be found
+		want int
+	}{
+		{
+			name:   "nil index",
+			index:  nil,
+			center: orb.Point{13.4, 52.5},
+			want:   -1,
+		},
+		{
+			name:   "empty index",
+			index:  NewPointIndex(nil),
+			center: orb.Point{13.4, 52.5},
+			want:   -1,
+		},
+		{
+			name:   "center on the only point",
+			index:  NewPointIndex(indexedPoints(orb.Point{13.4, 52.5})),
+			center: orb.Point{13.4, 52.5},
+			want:   0,
+		},
+		{
+			name:   "point within the initial radius",
+			index:  NewPointIndex(indexedPoints(orb.Point{13.4, 52.5}, orb.Point{13.401, 52.5})),
+			center: orb.Point{13.4009, 52.5},
+			want:   1,
+		},
+		{
+			name:   "points further away than the initial radius",
+			index:  NewPointIndex(indexedPoints(orb.Point{13.3, 52.5}, orb.Point{13.6, 52.5})),
+			center: orb.Point{13.4, 52.5},
+			want:   0,
+		},
+		{
+			name:   "center outside of the bound of the index",
+			index:  NewPointIndex(indexedPoints(orb.Point{13.4, 52.5}, orb.Point{13.5, 52.6})),
+			center: orb.Point{11.5, 48.1},
+			want:   0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			point, distance, found := test.index.Nearest(test.center)
+			if test.want == -1 {
+				if found || !math.IsInf(distance, 1) {
+					t.Fatalf("found %v at %fm in an index without points", point, distance)
+				}
+				return
+			}
+			if !found {
+				t.Fatal("found nothing")
+			}
+			want := test.index.Points()[test.want]
+			if point.ElementID != want.ElementID {
+				t.Errorf("got %v, want %v", point.Location, want.Location)
+			}
+		})
+	}
+}
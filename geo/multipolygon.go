@@ -0,0 +1,146 @@
+package geo
+
+import (
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/jkulzer/osm"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+var ErrRelationWithoutWays error = errors.New("Relation has no ways in the loaded data")
+var ErrUnclosedRing error = errors.New("Ways of the relation don't form closed rings")
+
+// builds the polygons of an OSM multipolygon or boundary relation.
+// the member ways get stitched into closed rings per role and every inner ring is put into the outer ring around it.
+// if some ways can't be closed into a ring, the polygons that could be built are returned together with ErrUnclosedRing
+func RelationToMultiPolygon(relationToConvert osm.Relation, nodes map[osm.NodeID]*osm.Node, ways map[osm.WayID]*osm.Way) (orb.MultiPolygon, error) {
+	var outerLineStrings []orb.LineString
+	var innerLineStrings []orb.LineString
+	for _, member := range relationToConvert.Members {
+		if member.Type != osm.TypeWay {
+			continue
+		}
+		way := ways[osm.WayID(member.Ref)]
+		if way == nil {
+			continue
+		}
+		lineString := LineStringFromWay(way, nodes)
+		if len(lineString) < 2 {
+			continue
+		}
+		switch member.Role {
+		// untagged members are treated as outer ways, like most renderers do
+		case "outer", "":
+			outerLineStrings = append(outerLineStrings, lineString)
+		case "inner":
+			innerLineStrings = append(innerLineStrings, lineString)
+		}
+	}
+
+	if len(outerLineStrings) == 0 {
+		return orb.MultiPolygon{}, ErrRelationWithoutWays
+	}
+
+	outerRings, outerClosed := stitchRings(outerLineStrings)
+	innerRings, innerClosed := stitchRings(innerLineStrings)
+
+	// outer rings are counterclockwise and inner rings clockwise, like GeoJSON expects
+	var multiPolygon orb.MultiPolygon
+	for _, ring := range outerRings {
+		if ring.Orientation() != orb.CCW {
+			ring.Reverse()
+		}
+		multiPolygon = append(multiPolygon, orb.Polygon{ring})
+	}
+
+	allInnersPlaced := true
+	for _, ring := range innerRings {
+		if ring.Orientation() != orb.CW {
+			ring.Reverse()
+		}
+		// with nested islands, the smallest outer ring around the inner ring is the one it belongs to
+		polygonIndex := -1
+		smallestArea := math.Inf(1)
+		for index, polygon := range multiPolygon {
+			if !ringInsideRing(ring, polygon[0]) {
+				continue
+			}
+			area := math.Abs(planar.Area(polygon[0]))
+			if area < smallestArea {
+				smallestArea = area
+				polygonIndex = index
+			}
+		}
+		if polygonIndex == -1 {
+			allInnersPlaced = false
+			continue
+		}
+		multiPolygon[polygonIndex] = append(multiPolygon[polygonIndex], ring)
+	}
+
+	if len(multiPolygon) == 0 || !outerClosed || !innerClosed || !allInnersPlaced {
+		return multiPolygon, ErrUnclosedRing
+	}
+	return multiPolygon, nil
+}
+
+// joins the line strings at their shared end points until they form closed rings.
+// returns false if some line strings were left over
+func stitchRings(lineStrings []orb.LineString) ([]orb.Ring, bool) {
+	// copies the line strings, reversing them would change the ones of the caller otherwise
+	remaining := make([]orb.LineString, len(lineStrings))
+	for index, lineString := range lineStrings {
+		remaining[index] = slices.Clone(lineString)
+	}
+
+	var rings []orb.Ring
+	allClosed := true
+	for len(remaining) > 0 {
+		current := remaining[0]
+		remaining = remaining[1:]
+
+		for !isClosed(current) {
+			matchIndex := -1
+			for index, lineString := range remaining {
+				switch current[len(current)-1] {
+				case lineString[0]:
+					matchIndex = index
+				case lineString[len(lineString)-1]:
+					lineString.Reverse()
+					matchIndex = index
+				}
+				if matchIndex != -1 {
+					break
+				}
+			}
+			if matchIndex == -1 {
+				break
+			}
+			current = append(current, remaining[matchIndex][1:]...)
+			remaining = slices.Delete(remaining, matchIndex, matchIndex+1)
+		}
+
+		if !isClosed(current) {
+			allClosed = false
+			continue
+		}
+		rings = append(rings, orb.Ring(current))
+	}
+	return rings, allClosed
+}
+
+func isClosed(lineString orb.LineString) bool {
+	return len(lineString) >= 4 && lineString[0] == lineString[len(lineString)-1]
+}
+
+// rings of a valid multipolygon don't cross, so checking a single point is enough
+func ringInsideRing(inner orb.Ring, outer orb.Ring) bool {
+	if !outer.Bound().Contains(inner[0]) {
+		return false
+	}
+	return planar.RingContains(outer, inner[0])
+}
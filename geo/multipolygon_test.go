@@ -0,0 +1,217 @@
+package geo
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jkulzer/osm"
+
+	"github.com/paulmach/orb"
+)
+
+func TestStitchRings(t *testing.T) {
+	tests := []struct {
+		name        string
+		lineStrings []orb.LineString
+		rings       int
+		allClosed   bool
+	}{
+		{
+			name: "closed way",
+			lineStrings: []orb.LineString{
+				{{0, 0}, {1, 0}, {1, 1}, {0, 0}},
+			},
+			rings:     1,
+			allClosed: true,
+		},
+		{
+			name: "ways in order",
+			lineStrings: []orb.LineString{
+				{{0, 0}, {1, 0}, {1, 1}},
+				{{1, 1}, {0, 1}, {0, 0}},
+			},
+			rings:     1,
+			allClosed: true,
+		},
+		{
+			name: "reversed way",
+			lineStrings: []orb.LineString{
+				{{0, 0}, {1, 0}, {1, 1}},
+				{{0, 0}, {0, 1}, {1, 1}},
+			},
+			rings:     1,
+			allClosed: true,
+		},
+		{
+			name: "two rings",
+			lineStrings: []orb.LineString{
+				{{0, 0}, {1, 0}, {1, 1}},
+				{{5, 5}, {6, 5}, {6, 6}, {5, 5}},
+				{{1, 1}, {0, 1}, {0, 0}},
+			},
+			rings:     2,
+			allClosed: true,
+		},
+		{
+			name: "gap",
+			lineStrings: []orb.LineString{
+				{{0, 0}, {1, 0}, {1, 1}},
+				{{0, 1}, {0, 0}},
+			},
+			rings:     0,
+			allClosed: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rings, allClosed := stitchRings(test.lineStrings)
+			if len(rings) != test.rings || allClosed != test.allClosed {
+				t.Fatalf("got %d rings and allClosed %v, want %d and %v", len(rings), allClosed, test.rings, test.allClosed)
+			}
+			for _, ring := range rings {
+				if !isClosed(orb.LineString(ring)) {
+					t.Errorf("ring %v isn't closed", ring)
+				}
+			}
+		})
+	}
+}
+
+func TestStitchRingsKeepsInput(t *testing.T) {
+	lineStrings := []orb.LineString{
+		{{0, 0}, {1, 0}, {1, 1}},
+		{{0, 0}, {0, 1}, {1, 1}},
+	}
+	stitchRings(lineStrings)
+	if lineStrings[1][0] != (orb.Point{0, 0}) {
+		t.Errorf("the line string of the caller got reversed: %v", lineStrings[1])
+	}
+}
+
+// builds the nodes and ways of a relation from line strings, one way per line string
+type relationBuilder struct {
+	nodes    map[osm.NodeID]*osm.Node
+	ways     map[osm.WayID]*osm.Way
+	relation osm.Relation
+}
+
+func newRelationBuilder() *relationBuilder {
+	return &relationBuilder{
+		nodes: make(map[osm.NodeID]*osm.Node),
+		ways:  make(map[osm.WayID]*osm.Way),
+	}
+}
+
+func (b *relationBuilder) addWay(role string, points ...orb.Point) {
+	way := &osm.Way{ID: osm.WayID(len(b.ways) + 1)}
+	for _, point := range points {
+		// points that appear in several ways share their node
+		nodeID := osm.NodeID(len(b.nodes) + 1)
+		for existingID, node := range b.nodes {
+			if node.Point() == point {
+				nodeID = existingID
+			}
+		}
+		b.nodes[nodeID] = &osm.Node{ID: nodeID, Lon: point.Lon(), Lat: point.Lat()}
+		way.Nodes = append(way.Nodes, osm.WayNode{ID: nodeID})
+	}
+	b.ways[way.ID] = way
+	b.relation.Members = append(b.relation.Members, osm.Member{Type: osm.TypeWay, Ref: int64(way.ID), Role: role})
+}
+
+func TestRelationToMultiPolygon(t *testing.T) {
+	tests := []struct {
+		name string
+		ways func(b *relationBuilder)
+		// the number of rings of every polygon
+		rings []int
+		err   error
+	}{
+		{
+			name: "outer ring of reversed ways",
+			ways: func(b *relationBuilder) {
+				b.addWay("outer", orb.Point{0, 0}, orb.Point{10, 0}, orb.Point{10, 10})
+				b.addWay("outer", orb.Point{0, 0}, orb.Point{0, 10}, orb.Point{10, 10})
+			},
+			rings: []int{1},
+		},
+		{
+			name: "inner ring",
+			ways: func(b *relationBuilder) {
+				b.addWay("outer", orb.Point{0, 0}, orb.Point{10, 0}, orb.Point{10, 10}, orb.Point{0, 10}, orb.Point{0, 0})
+				b.addWay("inner", orb.Point{2, 2}, orb.Point{4, 2}, orb.Point{4, 4}, orb.Point{2, 2})
+			},
+			rings: []int{2},
+		},
+		{
+			name: "inner ring goes into the smallest outer ring around it",
+			ways: func(b *relationBuilder) {
+				b.addWay("outer", orb.Point{0, 0}, orb.Point{10, 0}, orb.Point{10, 10}, orb.Point{0, 10}, orb.Point{0, 0})
+				b.addWay("inner", orb.Point{1, 1}, orb.Point{9, 1}, orb.Point{9, 9}, orb.Point{1, 9}, orb.Point{1, 1})
+				b.addWay("outer", orb.Point{3, 3}, orb.Point{7, 3}, orb.Point{7, 7}, orb.Point{3, 7}, orb.Point{3, 3})
+				b.addWay("inner", orb.Point{4, 4}, orb.Point{6, 4}, orb.Point{6, 6}, orb.Point{4, 4})
+			},
+			rings: []int{2, 2},
+		},
+		{
+			name: "untagged members are outer",
+			ways: func(b *relationBuilder) {
+				b.addWay("", orb.Point{0, 0}, orb.Point{10, 0}, orb.Point{10, 10}, orb.Point{0, 0})
+			},
+			rings: []int{1},
+		},
+		{
+			name: "inner ring outside of every outer ring",
+			ways: func(b *relationBuilder) {
+				b.addWay("outer", orb.Point{0, 0}, orb.Point{10, 0}, orb.Point{10, 10}, orb.Point{0, 10}, orb.Point{0, 0})
+				b.addWay("inner", orb.Point{20, 20}, orb.Point{22, 20}, orb.Point{22, 22}, orb.Point{20, 20})
+			},
+			rings: []int{1},
+			err:   ErrUnclosedRing,
+		},
+		{
+			name: "unclosed outer ring",
+			ways: func(b *relationBuilder) {
+				b.addWay("outer", orb.Point{0, 0}, orb.Point{10, 0}, orb.Point{10, 10})
+			},
+			rings: []int{},
+			err:   ErrUnclosedRing,
+		},
+		{
+			name: "only inner ways",
+			ways: func(b *relationBuilder) {
+				b.addWay("inner", orb.Point{2, 2}, orb.Point{4, 2}, orb.Point{4, 4}, orb.Point{2, 2})
+			},
+			rings: []int{},
+			err:   ErrRelationWithoutWays,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			builder := newRelationBuilder()
+			test.ways(builder)
+			multiPolygon, err := RelationToMultiPolygon(builder.relation, builder.nodes, builder.ways)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("got error %v, want %v", err, test.err)
+			}
+			if len(multiPolygon) != len(test.rings) {
+				t.Fatalf("got %d polygons, want %d", len(multiPolygon), len(test.rings))
+			}
+			for index, polygon := range multiPolygon {
+				if len(polygon) != test.rings[index] {
+					t.Errorf("polygon %d has %d rings, want %d", index, len(polygon), test.rings[index])
+				}
+				if polygon[0].Orientation() != orb.CCW {
+					t.Errorf("outer ring of polygon %d isn't counterclockwise", index)
+				}
+				for _, inner := range polygon[1:] {
+					if inner.Orientation() != orb.CW {
+						t.Errorf("inner ring of polygon %d isn't clockwise", index)
+					}
+				}
+			}
+		})
+	}
+}
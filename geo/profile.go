@@ -0,0 +1,175 @@
+package geo
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/jkulzer/osm"
+)
+
+var ErrInvalidRegionProfile error = errors.New("Invalid region profile")
+
+// describes which OSM data makes up the game area of a city
+type RegionProfile struct {
+	Name    string
+	PBFPath string
+	// where the preprocessed snapshot of the PBF file is cached. defaults to the PBF path with a .snapshot suffix
+	SnapshotPath string
+	// the relation of the city border
+	Boundary TagSelector
+	// the relations used by the area questions, e.g. Bezirke and Ortsteile in Berlin
+	Districts    TagSelector
+	Subdistricts TagSelector
+	// what the areas are called in the questions, e.g. "Bezirk" and "Ortsteil" in Berlin
+	DistrictName    string
+	SubdistrictName string
+	// lines that can be measured against, keyed by the short name used in the question URL
+	LineFeatures map[string]LineFeature
+	// points of interest that can be measured against, keyed by the short name used in the question URL
+	MeasuringCategories map[string]MeasuringCategory
+	Bounds              WorldBounds
+}
+
+// matches OSM objects by their tags
+type TagSelector struct {
+	// all of these tags have to be present. a value of "*" matches every value
+	Tags map[string]string
+	// objects whose name contains one of these strings get skipped
+	ExcludeNameContaining []string
+}
+
+type LineFeature struct {
+	// shown in the question list, e.g. "Closer to the Landwehrkanal"
+	Title string
+	// used in the history, e.g. "the Landwehrkanal"
+	LineName string
+	// relations whose member ways make up the line, e.g. waterway or route relations
+	Relations []TagSelector
+	// ways that belong to the line on their own, e.g. motorways
+	Ways []TagSelector
+}
+
+type MeasuringCategory struct {
+	// shown in the question list, e.g. "Closer to a hospital"
+	Title string
+	// used in the history, e.g. "a hospital"
+	ObjectName string
+	// used by the matching questions, e.g. "hospital"
+	Name string
+	// an object belongs to the category if it matches one of the filters
+	Filters []TagSelector
+	// some objects are only mapped as buildings, their nodes are e.g. pickup points
+	WaysOnly bool
+}
+
+// a rough box around the game area, used as the outside of the excluded area
+type WorldBounds struct {
+	Left   float64
+	Right  float64
+	Top    float64
+	Bottom float64
+}
+
+func LoadRegionProfile(path string) (RegionProfile, error) {
+	var profile RegionProfile
+
+	profileBytes, err := os.ReadFile(path)
+	if err != nil {
+		return profile, err
+	}
+	err = json.Unmarshal(profileBytes, &profile)
+	if err != nil {
+		return profile, err
+	}
+
+	if profile.PBFPath == "" || len(profile.Boundary.Tags) == 0 {
+		return profile, ErrInvalidRegionProfile
+	}
+	if profile.Bounds.Left >= profile.Bounds.Right || profile.Bounds.Bottom >= profile.Bounds.Top {
+		return profile, ErrInvalidRegionProfile
+	}
+	for lineKey, lineFeature := range profile.LineFeatures {
+		if lineKey == "" || lineFeature.Title == "" || len(lineFeature.Relations)+len(lineFeature.Ways) == 0 {
+			return profile, ErrInvalidRegionProfile
+		}
+		for _, selector := range slices.Concat(lineFeature.Relations, lineFeature.Ways) {
+			if len(selector.Tags) == 0 {
+				return profile, ErrInvalidRegionProfile
+			}
+		}
+	}
+	for categoryKey, category := range profile.MeasuringCategories {
+		if categoryKey == "" || category.Title == "" || len(category.Filters) == 0 {
+			return profile, ErrInvalidRegionProfile
+		}
+		for _, filter := range category.Filters {
+			if len(filter.Tags) == 0 {
+				return profile, ErrInvalidRegionProfile
+			}
+		}
+	}
+
+	return profile, nil
+}
+
+// the name of the areas of the level, with a generic name if the profile doesn't set one
+func (p RegionProfile) AreaLevelName(level AreaLevel) string {
+	switch level {
+	case DistrictLevel:
+		if p.DistrictName != "" {
+			return p.DistrictName
+		}
+		return "District"
+	case SubdistrictLevel:
+		if p.SubdistrictName != "" {
+			return p.SubdistrictName
+		}
+		return "Subdistrict"
+	}
+	return "Area"
+}
+
+func (s TagSelector) Matches(tags osm.Tags) bool {
+	if len(s.Tags) == 0 {
+		return false
+	}
+	for key, value := range s.Tags {
+		if !tags.HasTag(key) {
+			return false
+		}
+		if value != "*" && tags.Find(key) != value {
+			return false
+		}
+	}
+	name := tags.Find("name")
+	for _, excluded := range s.ExcludeNameContaining {
+		if strings.Contains(name, excluded) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l LineFeature) MatchesRelation(tags osm.Tags) bool {
+	return matchesAny(l.Relations, tags)
+}
+
+func (l LineFeature) MatchesWay(tags osm.Tags) bool {
+	return matchesAny(l.Ways, tags)
+}
+
+func (m MeasuringCategory) Matches(tags osm.Tags) bool {
+	return matchesAny(m.Filters, tags)
+}
+
+func matchesAny(selectors []TagSelector, tags osm.Tags) bool {
+	for _, selector := range selectors {
+		if selector.Matches(tags) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package geo
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/osm"
+)
+
+// has to be increased whenever the layout of the snapshot changes
+const snapshotVersion = 2
+
+var errSnapshotOutdated error = errors.New("Snapshot doesn't match the PBF file and region profile")
+
+type snapshotHeader struct {
+	Version    int
+	SourceHash string
+}
+
+type snapshotNode struct {
+	ID   osm.NodeID
+	Lat  float64
+	Lon  float64
+	Tags osm.Tags
+}
+
+type snapshotWay struct {
+	ID      osm.WayID
+	NodeIDs []osm.NodeID
+	Tags    osm.Tags
+}
+
+type snapshotMember struct {
+	Type osm.Type
+	Ref  int64
+	Role string
+}
+
+type snapshotRelation struct {
+	ID      osm.RelationID
+	Members []snapshotMember
+	Tags    osm.Tags
+}
+
+type snapshotBody struct {
+	Nodes     []snapshotNode
+	Ways      []snapshotWay
+	Relations []snapshotRelation
+}
+
+// loads the OSM data of the region from the snapshot. if the snapshot is missing or the PBF file changed,
+// the PBF file gets scanned and the snapshot is rebuilt
+func LoadData(profile RegionProfile) ProcessedData {
+	startTime := time.Now()
+
+	hash, err := sourceHash(profile)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed hashing " + profile.PBFPath)
+	}
+
+	collector, err := readSnapshot(profile, hash)
+	if err == nil {
+		log.Info().Msg("loaded snapshot " + SnapshotPath(profile) + " in " + time.Since(startTime).Round(time.Millisecond).String())
+		return collector.result()
+	}
+	if errors.Is(err, errSnapshotOutdated) || errors.Is(err, os.ErrNotExist) {
+		log.Info().Msg("snapshot " + SnapshotPath(profile) + " is missing or outdated, rebuilding it")
+	} else {
+		log.Err(err).Msg("failed reading snapshot " + SnapshotPath(profile) + ", rebuilding it")
+	}
+
+	collector = scanPBF(profile)
+	err = writeSnapshot(profile, hash, collector)
+	if err != nil {
+		log.Err(err).Msg("failed writing snapshot " + SnapshotPath(profile))
+	}
+	return collector.result()
+}
+
+// scans the PBF file and writes a new snapshot, regardless of whether the current one is up to date
+func Preprocess(profile RegionProfile) error {
+	hash, err := sourceHash(profile)
+	if err != nil {
+		return err
+	}
+	collector := scanPBF(profile)
+	return writeSnapshot(profile, hash, collector)
+}
+
+func SnapshotPath(profile RegionProfile) string {
+	if profile.SnapshotPath != "" {
+		return profile.SnapshotPath
+	}
+	return profile.PBFPath + ".snapshot"
+}
+
+// the snapshot depends on the content of the PBF file and on what the profile selects from it
+func sourceHash(profile RegionProfile) (string, error) {
+	hasher := sha256.New()
+
+	pbfFile, err := os.Open(profile.PBFPath)
+	if err != nil {
+		return "", err
+	}
+	defer pbfFile.Close()
+	_, err = io.Copy(hasher, pbfFile)
+	if err != nil {
+		return "", err
+	}
+
+	profileJson, err := json.Marshal(profile)
+	if err != nil {
+		return "", err
+	}
+	hasher.Write(profileJson)
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func readSnapshot(profile RegionProfile, hash string) (*dataCollector, error) {
+	snapshotFile, err := os.Open(SnapshotPath(profile))
+	if err != nil {
+		return nil, err
+	}
+	defer snapshotFile.Close()
+	decoder := gob.NewDecoder(bufio.NewReader(snapshotFile))
+
+	var header snapshotHeader
+	err = decoder.Decode(&header)
+	if err != nil {
+		return nil, err
+	}
+	if header.Version != snapshotVersion || header.SourceHash != hash {
+		return nil, errSnapshotOutdated
+	}
+
+	var body snapshotBody
+	err = decoder.Decode(&body)
+	if err != nil {
+		return nil, err
+	}
+
+	collector := newDataCollector(profile)
+	for _, node := range body.Nodes {
+		collector.add(&osm.Node{
+			ID:   node.ID,
+			Lat:  node.Lat,
+			Lon:  node.Lon,
+			Tags: node.Tags,
+		})
+	}
+	for _, way := range body.Ways {
+		wayNodes := make(osm.WayNodes, len(way.NodeIDs))
+		for index, nodeID := range way.NodeIDs {
+			wayNodes[index] = osm.WayNode{ID: nodeID}
+		}
+		collector.add(&osm.Way{
+			ID:    way.ID,
+			Nodes: wayNodes,
+			Tags:  way.Tags,
+		})
+	}
+	for _, relation := range body.Relations {
+		members := make(osm.Members, len(relation.Members))
+		for index, member := range relation.Members {
+			members[index] = osm.Member{
+				Type: member.Type,
+				Ref:  member.Ref,
+				Role: member.Role,
+			}
+		}
+		collector.add(&osm.Relation{
+			ID:      relation.ID,
+			Members: members,
+			Tags:    relation.Tags,
+		})
+	}
+	return collector, nil
+}
+
+func writeSnapshot(profile RegionProfile, hash string, collector *dataCollector) error {
+	var body snapshotBody
+
+	relations := collector.neededRelations()
+	ways := collector.neededWays(relations)
+	nodes := collector.neededNodes(relations, ways)
+
+	for _, node := range nodes {
+		body.Nodes = append(body.Nodes, snapshotNode{
+			ID:   node.ID,
+			Lat:  node.Lat,
+			Lon:  node.Lon,
+			Tags: node.Tags,
+		})
+	}
+	for _, way := range ways {
+		snapshotWay := snapshotWay{
+			ID:   way.ID,
+			Tags: way.Tags,
+		}
+		for _, wayNode := range way.Nodes {
+			snapshotWay.NodeIDs = append(snapshotWay.NodeIDs, wayNode.ID)
+		}
+		body.Ways = append(body.Ways, snapshotWay)
+	}
+	for _, relation := range relations {
+		snapshotRelation := snapshotRelation{
+			ID:   relation.ID,
+			Tags: relation.Tags,
+		}
+		for _, member := range relation.Members {
+			snapshotRelation.Members = append(snapshotRelation.Members, snapshotMember{
+				Type: member.Type,
+				Ref:  member.Ref,
+				Role: member.Role,
+			})
+		}
+		body.Relations = append(body.Relations, snapshotRelation)
+	}
+
+	// writes to a temporary file first so a crash never leaves a broken snapshot behind
+	path := SnapshotPath(profile)
+	temporaryPath := path + ".tmp"
+	snapshotFile, err := os.Create(temporaryPath)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(snapshotFile)
+	encoder := gob.NewEncoder(writer)
+
+	err = encoder.Encode(snapshotHeader{
+		Version:    snapshotVersion,
+		SourceHash: hash,
+	})
+	if err == nil {
+		err = encoder.Encode(body)
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	closeErr := snapshotFile.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(temporaryPath)
+		return err
+	}
+
+	log.Info().Msg(fmt.Sprint("wrote snapshot ", path, " with ", len(body.Nodes), " nodes, ", len(body.Ways), " ways and ", len(body.Relations), " relations"))
+	return os.Rename(temporaryPath, path)
+}
@@ -0,0 +1,221 @@
+package geo
+
+import (
+	"cmp"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/jkulzer/osm"
+
+	"github.com/paulmach/orb"
+)
+
+func testProfile(t *testing.T) RegionProfile {
+	return RegionProfile{
+		Name:         "Test",
+		SnapshotPath: filepath.Join(t.TempDir(), "test.snapshot"),
+		Boundary:     TagSelector{Tags: map[string]string{"boundary": "administrative", "admin_level": "4"}},
+		Districts:    TagSelector{Tags: map[string]string{"boundary": "administrative", "admin_level": "9"}},
+		Subdistricts: TagSelector{Tags: map[string]string{"boundary": "administrative", "admin_level": "10"}},
+		LineFeatures: map[string]LineFeature{
+			"spree": {
+				Title:     "Closer to the Spree",
+				Relations: []TagSelector{{Tags: map[string]string{"waterway": "river", "name": "Spree"}}},
+			},
+		},
+		MeasuringCategories: map[string]MeasuringCategory{
+			"museum": {
+				Title:   "Closer to a museum",
+				Filters: []TagSelector{{Tags: map[string]string{"tourism": "museum"}}},
+			},
+		},
+		Bounds: WorldBounds{Left: 12.9, Right: 13.3, Top: 52.7, Bottom: 52.3},
+	}
+}
+
+func testNode(id osm.NodeID, lon float64, lat float64, tags ...osm.Tag) *osm.Node {
+	return &osm.Node{ID: id, Lon: lon, Lat: lat, Tags: tags}
+}
+
+func testWay(id osm.WayID, nodeIDs []osm.NodeID, tags ...osm.Tag) *osm.Way {
+	way := &osm.Way{ID: id, Tags: tags}
+	for _, nodeID := range nodeIDs {
+		way.Nodes = append(way.Nodes, osm.WayNode{ID: nodeID})
+	}
+	return way
+}
+
+func testRelation(id osm.RelationID, members osm.Members, tags ...osm.Tag) *osm.Relation {
+	return &osm.Relation{ID: id, Members: members, Tags: tags}
+}
+
+// a small city with one object of everything the questions use and some objects nothing uses
+func testObjects() []osm.Object {
+	return []osm.Object{
+		// the city boundary, which is also the only district
+		testNode(1, 13.0, 52.4),
+		testNode(2, 13.2, 52.4),
+		testNode(3, 13.2, 52.6),
+		testNode(4, 13.0, 52.6),
+		testWay(100, []osm.NodeID{1, 2, 3, 4, 1}),
+		testRelation(200, osm.Members{{Type: osm.TypeWay, Ref: 100, Role: "outer"}},
+			osm.Tag{Key: "boundary", Value: "administrative"}, osm.Tag{Key: "admin_level", Value: "4"}, osm.Tag{Key: "name", Value: "Berlin"}),
+		testRelation(201, osm.Members{{Type: osm.TypeWay, Ref: 100, Role: "outer"}},
+			osm.Tag{Key: "boundary", Value: "administrative"}, osm.Tag{Key: "admin_level", Value: "9"}, osm.Tag{Key: "name", Value: "Mitte"}),
+
+		testNode(10, 13.1, 52.5, osm.Tag{Key: "railway", Value: "station"}, osm.Tag{Key: "name", Value: "Alexanderplatz"}),
+
+		// a museum node, a museum building and a museum way whose nodes are missing
+		testNode(11, 13.05, 52.45, osm.Tag{Key: "tourism", Value: "museum"}, osm.Tag{Key: "name", Value: "Pergamonmuseum"}),
+		testNode(20, 13.15, 52.55),
+		testNode(21, 13.16, 52.55),
+		testNode(22, 13.16, 52.56),
+		testWay(101, []osm.NodeID{20, 21, 22, 20}, osm.Tag{Key: "tourism", Value: "museum"}, osm.Tag{Key: "name", Value: "Neues Museum"}),
+		testWay(102, []osm.NodeID{998, 999}, osm.Tag{Key: "tourism", Value: "museum"}),
+
+		// a subway route with its stop
+		testNode(30, 13.05, 52.5),
+		testNode(31, 13.15, 52.5),
+		testNode(32, 13.06, 52.5, osm.Tag{Key: "railway", Value: "stop"}),
+		testWay(103, []osm.NodeID{30, 31}, osm.Tag{Key: "railway", Value: "subway"}),
+		testRelation(202, osm.Members{{Type: osm.TypeWay, Ref: 103}, {Type: osm.TypeNode, Ref: 32, Role: "stop"}},
+			osm.Tag{Key: "route", Value: "subway"}, osm.Tag{Key: "ref", Value: "U5"}),
+
+		// an address and a street for the geocoder
+		testNode(40, 13.1, 52.51, osm.Tag{Key: "addr:street", Value: "Unter den Linden"}, osm.Tag{Key: "addr:housenumber", Value: "1"}),
+		testNode(50, 13.1, 52.52),
+		testNode(51, 13.11, 52.52),
+		testWay(104, []osm.NodeID{50, 51}, osm.Tag{Key: "highway", Value: "residential"}, osm.Tag{Key: "name", Value: "Unter den Linden"}),
+
+		// the river
+		testNode(60, 13.0, 52.45),
+		testNode(61, 13.2, 52.47),
+		testWay(105, []osm.NodeID{60, 61}, osm.Tag{Key: "waterway", Value: "river"}),
+		testRelation(203, osm.Members{{Type: osm.TypeWay, Ref: 105, Role: "main_stream"}},
+			osm.Tag{Key: "waterway", Value: "river"}, osm.Tag{Key: "name", Value: "Spree"}),
+
+		// nothing uses these
+		testNode(90, 13.12, 52.42),
+		testNode(91, 13.13, 52.43),
+		testNode(92, 13.14, 52.44),
+		testWay(106, []osm.NodeID{90, 91}, osm.Tag{Key: "building", Value: "yes"}),
+		testRelation(204, osm.Members{{Type: osm.TypeWay, Ref: 106}}, osm.Tag{Key: "type", Value: "site"}),
+	}
+}
+
+// everything of the processed data the questions read, in an order that doesn't depend on map iteration
+type questionInputs struct {
+	GameArea      orb.Polygon
+	GameAreaBound orb.Bound
+	AdminAreas    map[AreaLevel][]AdminArea
+	LineFeatures  map[string][]orb.LineString
+	Indexes       map[string][]IndexedPoint
+	RouteStops    map[osm.RelationID][]orb.Point
+}
+
+func inputsOf(data ProcessedData) questionInputs {
+	sortedPoints := func(index *PointIndex) []IndexedPoint {
+		points := slices.Clone(index.Points())
+		slices.SortFunc(points, func(a, b IndexedPoint) int {
+			return cmp.Or(cmp.Compare(a.ElementID, b.ElementID), cmp.Compare(a.Location[0], b.Location[0]), cmp.Compare(a.Location[1], b.Location[1]))
+		})
+		return points
+	}
+
+	inputs := questionInputs{
+		GameArea:      data.GameArea,
+		GameAreaBound: data.GameAreaBound,
+		AdminAreas:    data.AdminAreas,
+		LineFeatures:  make(map[string][]orb.LineString),
+		Indexes: map[string][]IndexedPoint{
+			"stations":  sortedPoints(data.RailwayStationIndex),
+			"routes":    sortedPoints(data.RailRouteIndex),
+			"addresses": sortedPoints(data.AddressIndex),
+			"streets":   sortedPoints(data.StreetIndex),
+		},
+		RouteStops: make(map[osm.RelationID][]orb.Point),
+	}
+	for categoryKey, index := range data.CategoryIndexes {
+		inputs.Indexes[categoryKey] = sortedPoints(index)
+	}
+	for lineKey, lineStrings := range data.LineFeatures {
+		lineStrings = slices.Clone(lineStrings)
+		slices.SortFunc(lineStrings, func(a, b orb.LineString) int {
+			return cmp.Or(cmp.Compare(a[0][0], b[0][0]), cmp.Compare(a[0][1], b[0][1]))
+		})
+		inputs.LineFeatures[lineKey] = lineStrings
+	}
+	// the train service question looks the stops of a route up in the nodes
+	for routeID, route := range data.AllRailRoutes {
+		for _, member := range route.Members {
+			if node := data.Nodes[osm.NodeID(member.Ref)]; member.Type == osm.TypeNode && node != nil {
+				inputs.RouteStops[routeID] = append(inputs.RouteStops[routeID], node.Point())
+			}
+		}
+	}
+	return inputs
+}
+
+func TestSnapshotKeepsQuestionInputs(t *testing.T) {
+	profile := testProfile(t)
+	objects := testObjects()
+
+	// without wanted IDs the collector keeps every object, like the loader before the passes
+	fullCollector := newDataCollector(profile)
+	for _, object := range objects {
+		fullCollector.add(object)
+	}
+
+	passCollector := collectInPasses(profile, func(elementType osm.Type, handleObject func(osm.Object)) {
+		for _, object := range objects {
+			if object.ObjectID().Type() == elementType {
+				handleObject(object)
+			}
+		}
+	})
+	if _, ok := passCollector.nodes[92]; ok {
+		t.Error("the passes kept a node nothing uses")
+	}
+	if _, ok := passCollector.ways[106]; ok {
+		t.Error("the passes kept a way nothing uses")
+	}
+
+	err := writeSnapshot(profile, "hash", passCollector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshotCollector, err := readSnapshot(profile, "hash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = readSnapshot(profile, "other hash")
+	if err != errSnapshotOutdated {
+		t.Errorf("got error %v for a snapshot of another source, want %v", err, errSnapshotOutdated)
+	}
+
+	want := inputsOf(fullCollector.result())
+	if len(want.Indexes["museum"]) != 2 {
+		t.Errorf("got %d museums, want 2", len(want.Indexes["museum"]))
+	}
+	if len(want.RouteStops[202]) != 1 {
+		t.Errorf("got %d stops of the route, want 1", len(want.RouteStops[202]))
+	}
+
+	for name, collector := range map[string]*dataCollector{
+		"passes":   passCollector,
+		"snapshot": snapshotCollector,
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := inputsOf(collector.result())
+			gotValue := reflect.ValueOf(got)
+			wantValue := reflect.ValueOf(want)
+			for index := 0; index < gotValue.NumField(); index++ {
+				if !reflect.DeepEqual(gotValue.Field(index).Interface(), wantValue.Field(index).Interface()) {
+					t.Errorf("%s differs:\ngot  %v\nwant %v", gotValue.Type().Field(index).Name, gotValue.Field(index).Interface(), wantValue.Field(index).Interface())
+				}
+			}
+		})
+	}
+}
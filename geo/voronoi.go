@@ -0,0 +1,124 @@
+package geo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/paulmach/orb"
+)
+
+const earthRadius = 6378137.0
+
+// a flat projection in meters around an origin. good enough for the size of a city
+type localProjection struct {
+	origin orb.Point
+	cosLat float64
+}
+
+func newLocalProjection(origin orb.Point) localProjection {
+	return localProjection{
+		origin: origin,
+		cosLat: math.Cos(origin.Lat() * math.Pi / 180),
+	}
+}
+
+func (p localProjection) project(point orb.Point) orb.Point {
+	return orb.Point{
+		(point.Lon() - p.origin.Lon()) * math.Pi / 180 * earthRadius * p.cosLat,
+		(point.Lat() - p.origin.Lat()) * math.Pi / 180 * earthRadius,
+	}
+}
+
+func (p localProjection) unproject(point orb.Point) orb.Point {
+	return orb.Point{
+		p.origin.Lon() + point[0]/(earthRadius*p.cosLat)*180/math.Pi,
+		p.origin.Lat() + point[1]/earthRadius*180/math.Pi,
+	}
+}
+
+// returns the voronoi cell of the site, the area that is closer to the site than to every other point, clipped to the bound.
+// the cell is built by clipping the bound with the half plane of every neighbouring point
+func VoronoiCell(site orb.Point, points []orb.Point, bound orb.Bound) orb.Ring {
+	projection := newLocalProjection(site)
+
+	// the site is the origin of the projection
+	cell := []orb.Point{
+		projection.project(bound.Min),
+		projection.project(orb.Point{bound.Max.Lon(), bound.Min.Lat()}),
+		projection.project(bound.Max),
+		projection.project(orb.Point{bound.Min.Lon(), bound.Max.Lat()}),
+	}
+
+	var neighbours []orb.Point
+	for _, point := range points {
+		projected := projection.project(point)
+		// duplicates of the site don't have a bisector
+		if vectorLength(projected) < 0.01 {
+			continue
+		}
+		neighbours = append(neighbours, projected)
+	}
+	slices.SortFunc(neighbours, func(a, b orb.Point) int {
+		return cmp.Compare(vectorLength(a), vectorLength(b))
+	})
+
+	for _, neighbour := range neighbours {
+		// a bisector further away than the furthest corner of the cell can't cut it anymore, neither can the ones after it
+		if vectorLength(neighbour) > 2*maxVectorLength(cell) {
+			break
+		}
+		cell = clipHalfPlane(cell, neighbour)
+		if len(cell) == 0 {
+			break
+		}
+	}
+
+	var ring orb.Ring
+	for _, point := range cell {
+		ring = append(ring, projection.unproject(point))
+	}
+	if len(ring) > 0 {
+		ring = append(ring, ring[0])
+	}
+	return ring
+}
+
+// keeps the part of the convex polygon that is closer to the origin than to the neighbour
+func clipHalfPlane(polygon []orb.Point, neighbour orb.Point) []orb.Point {
+	// x·n <= |n|²/2
+	limit := (neighbour[0]*neighbour[0] + neighbour[1]*neighbour[1]) / 2
+	side := func(point orb.Point) float64 {
+		return point[0]*neighbour[0] + point[1]*neighbour[1] - limit
+	}
+
+	var clipped []orb.Point
+	for index, current := range polygon {
+		next := polygon[(index+1)%len(polygon)]
+		currentSide := side(current)
+		nextSide := side(next)
+		if currentSide <= 0 {
+			clipped = append(clipped, current)
+		}
+		if (currentSide <= 0) != (nextSide <= 0) {
+			t := currentSide / (currentSide - nextSide)
+			clipped = append(clipped, orb.Point{
+				current[0] + t*(next[0]-current[0]),
+				current[1] + t*(next[1]-current[1]),
+			})
+		}
+	}
+	return clipped
+}
+
+func vectorLength(point orb.Point) float64 {
+	return math.Hypot(point[0], point[1])
+}
+
+func maxVectorLength(polygon []orb.Point) float64 {
+	maxLength := 0.0
+	for _, point := range polygon {
+		maxLength = math.Max(maxLength, vectorLength(point))
+	}
+	return maxLength
+}
@@ -0,0 +1,127 @@
+package geo
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulmach/orb"
+	"github.com/paulmach/orb/planar"
+)
+
+func TestVoronoiCell(t *testing.T) {
+	bound := orb.Bound{Min: orb.Point{13.0, 52.0}, Max: orb.Point{14.0, 53.0}}
+
+	tests := []struct {
+		name   string
+		site   orb.Point
+		points []orb.Point
+		// points that have to be in the cell and points that can't be in it
+		inside  []orb.Point
+		outside []orb.Point
+		empty   bool
+	}{
+		{
+			name:   "no other points",
+			site:   orb.Point{13.5, 52.5},
+			inside: []orb.Point{{13.01, 52.01}, {13.99, 52.99}},
+		},
+		{
+			name:    "one neighbour",
+			site:    orb.Point{13.4, 52.5},
+			points:  []orb.Point{{13.6, 52.5}},
+			inside:  []orb.Point{{13.1, 52.5}, {13.49, 52.9}},
+			outside: []orb.Point{{13.51, 52.5}, {13.9, 52.1}},
+		},
+		{
+			name:    "surrounded site",
+			site:    orb.Point{13.5, 52.5},
+			points:  []orb.Point{{13.4, 52.5}, {13.6, 52.5}, {13.5, 52.4}, {13.5, 52.6}},
+			inside:  []orb.Point{{13.5, 52.5}, {13.54, 52.54}},
+			outside: []orb.Point{{13.1, 52.5}, {13.5, 52.9}},
+		},
+		{
+			name:   "duplicates of the site are ignored",
+			site:   orb.Point{13.5, 52.5},
+			points: []orb.Point{{13.5, 52.5}},
+			inside: []orb.Point{{13.01, 52.01}, {13.99, 52.99}},
+		},
+		{
+			name:    "collinear neighbours",
+			site:    orb.Point{13.5, 52.5},
+			points:  []orb.Point{{13.6, 52.5}, {13.7, 52.5}, {13.8, 52.5}},
+			inside:  []orb.Point{{13.54, 52.9}, {13.1, 52.1}},
+			outside: []orb.Point{{13.56, 52.5}},
+		},
+		{
+			name:   "site outside of the bound",
+			site:   orb.Point{15.0, 52.5},
+			points: []orb.Point{{13.5, 52.5}},
+			empty:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cell := VoronoiCell(test.site, test.points, bound)
+			if test.empty {
+				if len(cell) != 0 {
+					t.Fatalf("got cell %v, want an empty one", cell)
+				}
+				return
+			}
+			if len(cell) < 4 || cell[0] != cell[len(cell)-1] {
+				t.Fatalf("cell %v isn't a closed ring", cell)
+			}
+			if math.Abs(planar.Area(cell)) > planar.Area(bound.ToPolygon())+1e-9 {
+				t.Errorf("cell is larger than the bound")
+			}
+			for _, point := range test.inside {
+				if !planar.RingContains(cell, point) {
+					t.Errorf("cell doesn't contain %v", point)
+				}
+			}
+			for _, point := range test.outside {
+				if planar.RingContains(cell, point) {
+					t.Errorf("cell contains %v", point)
+				}
+			}
+		})
+	}
+}
+
+func TestClipHalfPlane(t *testing.T) {
+	square := []orb.Point{{-1, -1}, {1, -1}, {1, 1}, {-1, 1}}
+
+	tests := []struct {
+		name      string
+		neighbour orb.Point
+		// the area of the clipped square
+		area float64
+	}{
+		{name: "bisector cuts a quarter", neighbour: orb.Point{1, 0}, area: 3},
+		{name: "bisector on the edge towards the neighbour", neighbour: orb.Point{2, 0}, area: 4},
+		{name: "bisector outside", neighbour: orb.Point{10, 0}, area: 4},
+		{name: "bisector on the edge away from the neighbour", neighbour: orb.Point{-2, 0}, area: 4},
+		{name: "bisector behind the square", neighbour: orb.Point{-4, 0}, area: 4},
+		{name: "diagonal bisector", neighbour: orb.Point{1, 1}, area: 3.5},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clipped := clipHalfPlane(square, test.neighbour)
+			ring := orb.Ring(append(clipped, clipped[0]))
+			if area := math.Abs(planar.Area(ring)); math.Abs(area-test.area) > 1e-9 {
+				t.Errorf("got area %f, want %f", area, test.area)
+			}
+		})
+	}
+}
+
+func TestClipHalfPlaneRemovesEverything(t *testing.T) {
+	// the square lies completely on the side of the neighbour
+	square := []orb.Point{{4, -1}, {6, -1}, {6, 1}, {4, 1}}
+	clipped := clipHalfPlane(square, orb.Point{2, 0})
+	if len(clipped) != 0 {
+		t.Errorf("got %v, want nothing", clipped)
+	}
+}
@@ -7,10 +7,12 @@ require (
 	github.com/go-chi/chi/v5 v5.2.0
 	github.com/golang/geo v0.0.0-20230421003525-6adc56603217
 	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
 	github.com/jkulzer/osm v0.9.0
 	github.com/paulmach/orb v0.11.1
 	github.com/rs/zerolog v1.33.0
 	golang.org/x/crypto v0.32.0
+	gopkg.in/yaml.v3 v3.0.1
 	gorm.io/driver/sqlite v1.5.7
 	gorm.io/gorm v1.25.12
 )
@@ -26,7 +28,6 @@ require (
 	github.com/mattn/go-sqlite3 v1.14.22 // indirect
 	github.com/paulmach/protoscan v0.2.1 // indirect
 	github.com/stretchr/testify v1.9.0 // indirect
-	github.com/turistikrota/osm v0.0.5 // indirect
 	go.mongodb.org/mongo-driver v1.11.4 // indirect
 	golang.org/x/sys v0.29.0 // indirect
 	golang.org/x/text v0.21.0 // indirect
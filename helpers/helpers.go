@@ -13,6 +13,7 @@ import (
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/jkulzer/fib-server/events"
 	"github.com/jkulzer/fib-server/models"
 	"github.com/jkulzer/fib-server/sharedModels"
 	"github.com/paulmach/orb/geojson"
@@ -62,9 +63,16 @@ func FCToDB(db *gorm.DB, lobby models.Lobby, fc *geojson.FeatureCollection) erro
 		return result.Error
 	}
 	db.Session(&gorm.Session{FullSaveAssociations: true}).Updates(&lobby)
+	PublishMapUpdate(lobby)
 	return nil
 }
 
+// tells the clients about the excluded area of the lobby, only call it once the area is stored
+func PublishMapUpdate(lobby models.Lobby) {
+	events.Publish(lobby.ID, sharedModels.EventMapUpdate, json.RawMessage(lobby.ExcludedArea))
+}
+
+// stores the excluded area in the lobby struct, the caller saves the lobby and publishes the map update
 func SaveFC(lobby models.Lobby, fc *geojson.FeatureCollection) (models.Lobby, error) {
 	areaJson, err := fc.MarshalJSON()
 	if err != nil {
@@ -113,6 +121,12 @@ func ExternalToInternalCard(externalCard sharedModels.Card) models.Card {
 		ExpirationDuration: externalCard.ExpirationDuration,
 		ActivationTime:     externalCard.ActivationTime,
 		BonusTime:          externalCard.BonusTime,
+		PenaltyTime:        externalCard.PenaltyTime,
+		Effect:             externalCard.Effect,
+		Resolvable:         externalCard.Resolvable,
+
+		CastingCostDescription: externalCard.CastingCostDescription,
+		CastingCost:            externalCard.CastingCost,
 	}
 }
 
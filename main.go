@@ -1,9 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 
 	"github.com/rs/zerolog/log"
 
@@ -11,25 +14,72 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 
 	"github.com/jkulzer/fib-server/db"
+	"github.com/jkulzer/fib-server/decks"
 	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/questions"
 	"github.com/jkulzer/fib-server/routes"
+	"github.com/jkulzer/fib-server/scheduler"
 )
 
 func main() {
 	port := 3001
 
+	// the command is optional so that running the bare binary keeps serving
+	command := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command = args[0]
+		args = args[1:]
+	}
+
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	regionPath := flags.String("region", "./regions/berlin.json", "path to the region profile of the game area")
+	uploadDir := flags.String("uploads", "./uploads", "directory where uploaded photos get stored")
+	packDir := flags.String("packs", "./packs", "directory with the deck packs lobbies can play with")
+	flags.Parse(args)
+
+	profile, err := geo.LoadRegionProfile(*regionPath)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load region profile " + *regionPath)
+	}
+
+	switch command {
+	case "preprocess":
+		err = geo.Preprocess(profile)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to preprocess " + profile.PBFPath)
+		}
+		return
+	case "serve":
+	default:
+		log.Fatal().Msg("unknown command " + command + ", expected serve or preprocess")
+	}
+
+	packs, err := decks.LoadPacks(*packDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to load deck packs from " + *packDir)
+	}
+
 	db := db.InitDB()
 
+	sched := scheduler.New(db)
+	sched.Handle(models.EventAnswerDeadline, questions.HandleAnswerDeadline)
+	err = sched.Recover()
+	if err != nil {
+		log.Err(err).Msg("failed to recover scheduled events")
+	}
+
 	r := chi.NewRouter()
 
 	r.Use(middleware.Logger)
 
-	processedData := geo.ProcessData()
+	processedData := geo.LoadData(profile)
 
-	routes.Router(r, db, processedData)
+	routes.Router(r, db, processedData, sched, geo.NewLocalGeocoder(processedData), *uploadDir, packs)
 
 	fmt.Println("Listening on :" + strconv.Itoa(port))
-	err := http.ListenAndServe("0.0.0.0:"+strconv.Itoa(port), r)
+	err = http.ListenAndServe("0.0.0.0:"+strconv.Itoa(port), r)
 	if err != nil {
 		log.Err(err)
 	}
@@ -1,12 +1,17 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"time"
+
 	"github.com/google/uuid"
 
 	"gorm.io/gorm"
 
-	"time"
+	"github.com/rs/zerolog/log"
 
+	"github.com/jkulzer/fib-server/events"
 	"github.com/jkulzer/fib-server/sharedModels"
 )
 
@@ -35,9 +40,12 @@ type Lobby struct {
 	SeekerID            uint
 	Seeker              UserAccount `gorm:"foreignKey:SeekerID"`
 	Phase               sharedModels.GamePhase
+	Settings            sharedModels.GameSettings `gorm:"embedded;embeddedPrefix:settings_"`
 	HiderReady          bool
 	SeekerReady         bool
 	RunStartTime        time.Time
+	FoundTime           time.Time
+	HidingTime          time.Duration
 	ZoneCenterLat       float64
 	ZoneCenterLon       float64
 	HiderLat            float64
@@ -48,9 +56,11 @@ type Lobby struct {
 	ThermometerDistance float64
 	ThermometerStartLat float64
 	ThermometerStartLon float64
-	History             []HistoryInDB `gorm:"foreignKey:LobbyID"`
-	HiderDeck           []Card        `gorm:"foreignKey:HiderDeckLobbyID"`
-	PlayedCurseList     []Card        `gorm:"foreignKey:PlayedCurseID"`
+	// the hider can be outside of the hiding zone until then because they are moving to a new one
+	ZoneMoveDeadline time.Time
+	History          []HistoryInDB `gorm:"foreignKey:LobbyID"`
+	HiderDeck        []Card        `gorm:"foreignKey:HiderDeckLobbyID"`
+	PlayedCurseList  []Card        `gorm:"foreignKey:PlayedCurseID"`
 	// opportunities to draw cards
 	CardDraws []CardDraw `gorm:"foreignKey:LobbyID"`
 	// drawn cards from which the selection hasn't been made
@@ -59,12 +69,63 @@ type Lobby struct {
 	RemainingCards []Card `gorm:"foreignKey:RemainingCardsLobbyID"`
 }
 
+// lobbies created before settings existed get the default settings
+func (l *Lobby) AfterFind(tx *gorm.DB) error {
+	if l.Settings == (sharedModels.GameSettings{}) {
+		l.Settings = sharedModels.DefaultGameSettings()
+	}
+	// lobbies created before deck packs existed play with the cards of the default pack
+	if l.Settings.DeckPack == "" {
+		l.Settings.DeckPack = sharedModels.DefaultDeckPack
+	}
+	return nil
+}
+
+// the phase only gets written by the scheduler, so that saving a lobby that was loaded before a phase change
+// doesn't move it back to the old phase
+func (l *Lobby) BeforeUpdate(tx *gorm.DB) error {
+	if _, isColumnUpdate := tx.Statement.Dest.(map[string]interface{}); !isColumnUpdate {
+		tx.Statement.Omits = append(tx.Statement.Omits, "phase", "run_start_time")
+	}
+	return nil
+}
+
 type HistoryInDB struct {
 	gorm.Model
 	LobbyID     uint
 	LobbyType   string
 	Title       string
 	Description string
+	// photo attached to the history item, 0 if there is none
+	PhotoID uint
+}
+
+// an image uploaded by a player, the file lives in the upload directory
+type Photo struct {
+	gorm.Model
+	LobbyID     uint
+	Path        string
+	ContentType string
+}
+
+// a question the seeker asked. questions the server evaluates get answered right away,
+// the others stay asked until the hider answers them or the deadline passes
+type AskedQuestion struct {
+	gorm.Model
+	LobbyID uint
+	// ID of the question in the registry, e.g. "radar"
+	QuestionID string
+	Title      string
+	Category   sharedModels.QuestionCategory
+	State      sharedModels.QuestionState
+	Deadline   time.Time
+	// the answer that ends up in the history
+	Answer string
+	// photo the hider answered with, 0 if there is none
+	PhotoID uint
+	// the reward of the hider once the question is answered
+	CardsToDraw uint
+	CardsToPick uint
 }
 
 type CurrentDraw struct {
@@ -86,6 +147,22 @@ type Card struct {
 	ExpirationDuration    time.Duration
 	ActivationTime        time.Time
 	BonusTime             time.Duration
+	// time the hider gets added to their score if the seeker fails the curse
+	PenaltyTime    time.Duration
+	PenaltyApplied bool
+	// key of the effect that enforces the curse, see the curses package
+	Effect string
+	// the json encoded parameters the hider chose when playing the curse
+	Parameters string
+	Resolvable bool
+	CurseState sharedModels.CurseState
+	// the description is for the players, the cost itself gets enforced by the server
+	CastingCostDescription string
+	CastingCost            sharedModels.CastingCost `gorm:"embedded;embeddedPrefix:casting_cost_"`
+	// the photo the hider paid the casting cost with
+	PhotoID uint
+	// when the seeker reached the destination of a travel agent curse, zero while they aren't there
+	DestinationReachedTime time.Time
 }
 
 func (c *Card) DTO() sharedModels.Card {
@@ -97,7 +174,39 @@ func (c *Card) DTO() sharedModels.Card {
 		ExpirationDuration: c.ExpirationDuration,
 		ActivationTime:     c.ActivationTime,
 		BonusTime:          c.BonusTime,
+		PenaltyTime:        c.PenaltyTime,
+		PenaltyApplied:     c.PenaltyApplied,
+		Effect:             c.Effect,
+		Parameters:         c.CurseParameters(),
+		Resolvable:         c.Resolvable,
+		State:              c.CurseState,
+
+		CastingCostDescription: c.CastingCostDescription,
+		CastingCost:            c.CastingCost,
+		PhotoID:                c.PhotoID,
+		DestinationReachedTime: c.DestinationReachedTime,
+	}
+}
+
+func (c *Card) CurseParameters() sharedModels.CurseParameters {
+	var parameters sharedModels.CurseParameters
+	if c.Parameters == "" {
+		return parameters
 	}
+	err := json.Unmarshal([]byte(c.Parameters), &parameters)
+	if err != nil {
+		log.Err(err).Msg("failed to parse parameters of card " + fmt.Sprint(c.ID))
+	}
+	return parameters
+}
+
+// whether the curse still applies. curses without an expiration duration last until they are resolved
+func (c *Card) IsActive(now time.Time) bool {
+	if c.CurseState != sharedModels.CurseActive {
+		return false
+	}
+	// the expiry event might not have fired yet
+	return c.ExpirationDuration == 0 || now.Before(c.ActivationTime.Add(c.ExpirationDuration))
 }
 
 type CardDraw struct {
@@ -107,6 +216,66 @@ type CardDraw struct {
 	CardsToPick uint
 }
 
+func (h *HistoryInDB) AfterCreate(tx *gorm.DB) error {
+	events.Queue(tx, h.LobbyID, sharedModels.EventHistory, h.DTO())
+	return nil
+}
+
+func (h *HistoryInDB) DTO() sharedModels.HistoryItem {
+	return sharedModels.HistoryItem{
+		Title:       h.Title,
+		Description: h.Description,
+		PhotoID:     h.PhotoID,
+	}
+}
+
+func (q *AskedQuestion) DTO() sharedModels.AskedQuestion {
+	return sharedModels.AskedQuestion{
+		ID:         q.ID,
+		QuestionID: q.QuestionID,
+		Title:      q.Title,
+		Category:   q.Category,
+		State:      q.State,
+		AskedAt:    q.CreatedAt,
+		Deadline:   q.Deadline,
+		Answer:     q.Answer,
+		PhotoID:    q.PhotoID,
+	}
+}
+
+// clients get notified when a question is asked and when its state changes
+func (q *AskedQuestion) AfterSave(tx *gorm.DB) error {
+	events.Queue(tx, q.LobbyID, sharedModels.EventQuestion, q.DTO())
+	return nil
+}
+
+func (c *CardDraw) AfterCreate(tx *gorm.DB) error {
+	events.Queue(tx, c.LobbyID, sharedModels.EventCardDraw, sharedModels.CardDraw{
+		DrawID:      c.ID,
+		CardsToDraw: c.CardsToDraw,
+		CardsToPick: c.CardsToPick,
+	})
+	return nil
+}
+
+// a deadline that is stored in the db so it survives restarts
+type ScheduledEvent struct {
+	gorm.Model
+	LobbyID uint
+	Type    ScheduledEventType
+	// ID of the object the event refers to, e.g. a card or a question
+	RefID  uint
+	FireAt time.Time
+}
+
+type ScheduledEventType int
+
+const (
+	EventRunEnd ScheduledEventType = iota
+	EventCurseExpiry
+	EventAnswerDeadline
+)
+
 type ContextKey uint
 
 const (
@@ -0,0 +1,244 @@
+package questions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/scheduler"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+var ErrQuestionPending error = errors.New("Another question is still waiting for the answer of the hider")
+
+var ErrQuestionNotOpen error = errors.New("Question isn't waiting for an answer")
+
+var ErrAnswerDeadlinePassed error = errors.New("Deadline for the answer has passed")
+
+var ErrPhotoAnswer error = errors.New("Photo questions are answered by uploading the photo")
+
+var ErrInvalidAnswer error = errors.New("Answer must not be empty or longer than 500 characters")
+
+const maxAnswerLength = 500
+
+// a question the hider answers instead of the server, e.g. a photo.
+// its Ask only checks the parameters and describes what the seeker asked for
+type HiderAnswered interface {
+	// how long the hider has to answer
+	AnswerTime() time.Duration
+}
+
+// returns the question of the lobby that is still waiting for an answer, if there is one
+func OpenQuestion(db *gorm.DB, lobbyID uint) (models.AskedQuestion, bool, error) {
+	var askedQuestions []models.AskedQuestion
+	result := db.Where("lobby_id = ? AND state = ?", lobbyID, sharedModels.QuestionAsked).Limit(1).Find(&askedQuestions)
+	if result.Error != nil {
+		return models.AskedQuestion{}, false, result.Error
+	}
+	if len(askedQuestions) == 0 {
+		return models.AskedQuestion{}, false, nil
+	}
+	return askedQuestions[0], true, nil
+}
+
+// checks that the question still waits for the answer of the hider
+func checkAnswerable(askedQuestion models.AskedQuestion) error {
+	if askedQuestion.State != sharedModels.QuestionAsked {
+		return ErrQuestionNotOpen
+	}
+	if time.Now().After(askedQuestion.Deadline) {
+		return ErrAnswerDeadlinePassed
+	}
+	return nil
+}
+
+// stores the answer of the hider, adds it to the history and gives the hider the reward of the question
+func answerQuestion(db *gorm.DB, env Env, askedQuestion *models.AskedQuestion, answer string, photoID uint, w http.ResponseWriter) error {
+	askedQuestion.State = sharedModels.QuestionAnswered
+	askedQuestion.Answer = answer
+	askedQuestion.PhotoID = photoID
+	result := db.Save(askedQuestion)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	err := env.Scheduler.Cancel(askedQuestion.LobbyID, models.EventAnswerDeadline, askedQuestion.ID)
+	if err != nil {
+		log.Err(err).Msg("failed cancelling answer deadline of question " + fmt.Sprint(askedQuestion.ID))
+	}
+
+	historyItem := models.HistoryInDB{
+		LobbyID:     askedQuestion.LobbyID,
+		Title:       askedQuestion.Title,
+		Description: answer,
+		PhotoID:     photoID,
+	}
+	result = db.Create(&historyItem)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if askedQuestion.CardsToDraw > 0 {
+		return helpers.CreateCardDraw(db, askedQuestion.CardsToDraw, askedQuestion.CardsToPick, askedQuestion.LobbyID, w)
+	}
+	return nil
+}
+
+// stores the answer of the hider for an open question that isn't a photo question
+func answerHandler(db *gorm.DB, env Env) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+		if !isLobby {
+			log.Debug().Msg(fmt.Sprint(lobby))
+			log.Warn().Msg("couldn't cast lobby value from context")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+		if !isUint {
+			log.Warn().Msg("failed to convert userID to uint in question answer")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		if userID != lobby.HiderID {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(nil)
+			return
+		}
+
+		askedQuestionID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(nil)
+			return
+		}
+		var answerRequest sharedModels.AnswerQuestionRequest
+		err = json.NewDecoder(r.Body).Decode(&answerRequest)
+		if err != nil {
+			log.Warn().Msg("failed decoding answer request: " + err.Error())
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(nil)
+			return
+		}
+		answer := strings.TrimSpace(answerRequest.Answer)
+		if answer == "" || utf8.RuneCountInString(answer) > maxAnswerLength {
+			w.WriteHeader(statusCodeForError(ErrInvalidAnswer))
+			w.Write([]byte(ErrInvalidAnswer.Error()))
+			return
+		}
+
+		var askedQuestion models.AskedQuestion
+		result := db.Where("lobby_id = ?", lobby.ID).First(&askedQuestion, askedQuestionID)
+		if result.Error != nil {
+			log.Warn().Msg("question " + fmt.Sprint(askedQuestionID) + " doesn't exist in lobby " + lobby.Token)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(nil)
+			return
+		}
+		if askedQuestion.Category == sharedModels.CategoryPhoto {
+			w.WriteHeader(statusCodeForError(ErrPhotoAnswer))
+			w.Write([]byte(ErrPhotoAnswer.Error()))
+			return
+		}
+
+		err = checkAnswerable(askedQuestion)
+		if err != nil {
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		err = answerQuestion(db, env, &askedQuestion, "Hider answered \""+answer+"\"", 0, w)
+		if err != nil {
+			log.Err(err).Msg("failed answering question " + fmt.Sprint(askedQuestion.ID))
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		writeAskedQuestion(w, http.StatusOK, askedQuestion)
+	}
+}
+
+// marks the question as expired if the hider didn't answer it in time
+func HandleAnswerDeadline(s *scheduler.Scheduler, event models.ScheduledEvent) error {
+	db := s.DB()
+
+	var askedQuestion models.AskedQuestion
+	result := db.First(&askedQuestion, event.RefID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if askedQuestion.State != sharedModels.QuestionAsked {
+		return nil
+	}
+
+	askedQuestion.State = sharedModels.QuestionExpired
+	result = db.Save(&askedQuestion)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	log.Info().Msg("question " + fmt.Sprint(askedQuestion.ID) + " of lobby " + fmt.Sprint(askedQuestion.LobbyID) + " expired")
+
+	historyItem := models.HistoryInDB{
+		LobbyID:     askedQuestion.LobbyID,
+		Title:       askedQuestion.Title,
+		Description: "Hider didn't answer in time",
+	}
+	return db.Create(&historyItem).Error
+}
+
+// lists all questions that were asked in the lobby
+func askedQuestionsHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+		if !isLobby {
+			log.Debug().Msg(fmt.Sprint(lobby))
+			log.Warn().Msg("couldn't cast lobby value from context")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		var askedQuestions []models.AskedQuestion
+		result := db.Where("lobby_id = ?", lobby.ID).Order("created_at").Find(&askedQuestions)
+		if result.Error != nil {
+			log.Err(result.Error).Msg("failed loading asked questions")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		response := sharedModels.AskedQuestionListResponse{
+			Questions: []sharedModels.AskedQuestion{},
+		}
+		for _, askedQuestion := range askedQuestions {
+			response.Questions = append(response.Questions, askedQuestion.DTO())
+		}
+		marshalledResponse, err := json.Marshal(response)
+		if err != nil {
+			log.Err(err).Msg("failed to marshal asked question list response")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(marshalledResponse)
+	}
+}
@@ -0,0 +1,63 @@
+package questions
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+// a question the server can't answer from its data, the hider answers it with a text
+type HiderQuestion struct {
+	Key      string
+	Title    string
+	Category sharedModels.QuestionCategory
+	// what the seeker asks, e.g. "Is the hider on the same street as the seeker?"
+	Prompt string
+	// how long the hider has to answer
+	Time time.Duration
+}
+
+var HiderQuestions = []HiderQuestion{
+	{
+		Key:      "sameStreet",
+		Title:    "Same street",
+		Category: sharedModels.CategoryMatching,
+		Prompt:   "Is the hider on the same street as the seeker?",
+		Time:     5 * time.Minute,
+	},
+	{
+		Key:      "closerToWater",
+		Title:    "Closer to a body of water",
+		Category: sharedModels.CategoryMeasuring,
+		Prompt:   "Is the hider closer to a body of water than the seeker?",
+		Time:     5 * time.Minute,
+	},
+	{
+		Key:      "higherAltitude",
+		Title:    "Higher altitude",
+		Category: sharedModels.CategoryMeasuring,
+		Prompt:   "Is the hider higher above sea level than the seeker?",
+		Time:     5 * time.Minute,
+	},
+}
+
+func (q HiderQuestion) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "hider/" + q.Key,
+		Path:     "/hider/" + q.Key,
+		Title:    q.Title,
+		Category: q.Category,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 3, CardsToPick: 1},
+	}
+}
+
+func (q HiderQuestion) AnswerTime() time.Duration {
+	return q.Time
+}
+
+func (q HiderQuestion) Ask(request Request) (Answer, error) {
+	return Answer{
+		Description: "Seeker asked \"" + q.Prompt + "\", the hider has " + fmt.Sprint(q.Time) + " to answer",
+	}, nil
+}
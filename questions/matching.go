@@ -0,0 +1,279 @@
+package questions
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb"
+	orbGeo "github.com/paulmach/orb/geo"
+
+	"github.com/engelsjk/polygol"
+)
+
+// does a train route that stops near the seeker also stop in the hiding zone?
+type TrainService struct{}
+
+func (q TrainService) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "trainService",
+		Path:     "/trainService",
+		Title:    "Train Service",
+		Category: sharedModels.CategoryMatching,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 3, CardsToPick: 1},
+	}
+}
+
+func (q TrainService) Ask(request Request) (Answer, error) {
+	lobby := request.Lobby
+	data := request.Env.Data
+
+	body, err := helpers.ReadHttpResponse(request.HTTP.Body)
+	if err != nil {
+		log.Err(err).Msg("failed to read http request of body " + fmt.Sprint(err))
+	}
+
+	var trainServiceRequest sharedModels.TrainServiceRequest
+	err = json.Unmarshal(body, &trainServiceRequest)
+	if err != nil {
+		log.Warn().Msg("failed to parse json of train service request")
+		return Answer{}, sharedModels.ErrInvalidQuestionParameter
+	}
+
+	route := data.AllRailRoutes[trainServiceRequest.RouteID]
+	if route == nil {
+		return Answer{}, sharedModels.ErrFeatureNotInRegion
+	}
+
+	zoneCenter := zoneCenterPoint(lobby)
+
+	var stopPositions []orb.Point
+	for _, member := range route.Members {
+		if member.Type != "node" {
+			continue
+		}
+		memberNodeID, err := member.ElementID().NodeID()
+		if err != nil {
+			log.Err(err).Msg("element id: " + fmt.Sprint(member.ElementID()))
+			continue
+		}
+		memberNode := data.Nodes[memberNodeID]
+		if memberNode == nil {
+			continue
+		}
+		if memberNode.Tags.Find("railway") != "stop" {
+			continue
+		}
+		stopPositions = append(stopPositions, helpers.NodeToPoint(*memberNode))
+	}
+
+	isOnLine := false
+	for _, stopPosition := range stopPositions {
+		if orbGeo.DistanceHaversine(stopPosition, zoneCenter) <= lobby.Settings.HidingZoneRadius {
+			isOnLine = true
+		}
+	}
+
+	var circleGeomList []polygol.Geom
+	var circleList []orb.Ring
+	for _, stopPosition := range stopPositions {
+		var circle orb.Ring
+		if isOnLine {
+			circle = helpers.NewCircle(stopPosition, lobby.Settings.HidingZoneRadius*2)
+		} else {
+			circle = helpers.NewCircle(stopPosition, lobby.Settings.HidingZoneRadius)
+		}
+		circleList = append(circleList, circle)
+		circleGeomList = append(circleGeomList, helpers.G2p(orb.Polygon{circle}))
+	}
+
+	var answer Answer
+	if isOnLine {
+		outsideGeom := helpers.G2p(orb.Polygon{sharedModels.WideOutsideBound()})
+		diff, err := polygol.Difference(outsideGeom, circleGeomList...)
+		if err != nil {
+			log.Err(err).Msg("failed to make union of circles for train service question")
+			return Answer{}, err
+		}
+		exclusionPolygons := helpers.P2g(diff)
+
+		for index := range exclusionPolygons {
+			if index < len(exclusionPolygons)-1 {
+				exclusionPolygons[index] = append(exclusionPolygons[index], sharedModels.WideOutsideBound())
+			}
+		}
+		answer.Exclusions = append(answer.Exclusions, exclusionPolygons)
+		answer.Description = route.Tags.Find("name") + " stops in the hiding zone"
+	} else {
+		for _, circle := range circleList {
+			answer.Exclusions = append(answer.Exclusions, circle)
+		}
+		answer.Description = route.Tags.Find("name") + " doesn't stop in the hiding zone"
+	}
+	return answer, nil
+}
+
+// is the hider in the same administrative area as the seeker?
+type SameArea struct {
+	ID string
+	// what the areas are called in the region, e.g. "Bezirk"
+	LevelName string
+	Level     geo.AreaLevel
+}
+
+func (q SameArea) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       q.ID,
+		Path:     "/" + q.ID,
+		Title:    "Same " + q.LevelName,
+		Category: sharedModels.CategoryMatching,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 3, CardsToPick: 1},
+	}
+}
+
+func (q SameArea) Ask(request Request) (Answer, error) {
+	data := request.Env.Data
+
+	seekerArea, _ := data.LocateArea(seekerPoint(request.Lobby), q.Level)
+	hiderArea, _ := data.LocateArea(hiderPoint(request.Lobby), q.Level)
+
+	log.Debug().Msg("seeker area is " + seekerArea.Name + " and hider area is " + hiderArea.Name)
+
+	var answer Answer
+	if hiderArea.Name == seekerArea.Name {
+		answer.Description = "Hider is in " + hiderArea.Name
+		for _, area := range data.AdminAreas[q.Level] {
+			if area.Name != hiderArea.Name {
+				answer.Exclusions = append(answer.Exclusions, area.Polygon)
+			}
+		}
+	} else {
+		answer.Description = "Hider is not in " + seekerArea.Name
+		for _, area := range data.AdminAreas[q.Level] {
+			if area.Name == seekerArea.Name {
+				answer.Exclusions = append(answer.Exclusions, area.Polygon)
+			}
+		}
+	}
+	return answer, nil
+}
+
+// does the name of the hiders area end with the same letter as the one of the seeker?
+type AreaLastLetter struct {
+	ID string
+	// what the areas are called in the region, e.g. "Bezirk"
+	LevelName string
+	Level     geo.AreaLevel
+}
+
+func (q AreaLastLetter) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       q.ID,
+		Path:     "/" + q.ID,
+		Title:    q.LevelName + " last letter",
+		Category: sharedModels.CategoryMatching,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 3, CardsToPick: 1},
+	}
+}
+
+func (q AreaLastLetter) Ask(request Request) (Answer, error) {
+	data := request.Env.Data
+
+	seekerArea, seekerInArea := data.LocateArea(seekerPoint(request.Lobby), q.Level)
+	hiderArea, hiderInArea := data.LocateArea(hiderPoint(request.Lobby), q.Level)
+	if !seekerInArea || !hiderInArea {
+		log.Warn().Msg("seeker or hider isn't in any area")
+		return Answer{}, sharedModels.ErrFeatureNotInRegion
+	}
+
+	log.Debug().Msg("seeker area is " + seekerArea.Name + " and hider area is " + hiderArea.Name)
+
+	seekerLetter := seekerArea.Name[len(seekerArea.Name)-1]
+	hiderLetter := hiderArea.Name[len(hiderArea.Name)-1]
+
+	var answer Answer
+	if hiderLetter == seekerLetter {
+		answer.Description = "Hiders " + q.LevelName + " ends with " + string(hiderLetter)
+		for _, area := range data.AdminAreas[q.Level] {
+			if area.Name[len(area.Name)-1] != hiderLetter {
+				answer.Exclusions = append(answer.Exclusions, area.Polygon)
+			}
+		}
+	} else {
+		answer.Description = "Hiders " + q.LevelName + " doesn't end with " + string(seekerLetter)
+		for _, area := range data.AdminAreas[q.Level] {
+			if area.Name[len(area.Name)-1] == seekerLetter {
+				answer.Exclusions = append(answer.Exclusions, area.Polygon)
+			}
+		}
+	}
+	return answer, nil
+}
+
+// is the hiders nearest object of a category the same as the one of the seeker?
+type SameNearest struct {
+	Points PointCategory
+}
+
+func (q SameNearest) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "matching/" + q.Points.Key,
+		Path:     "/matching/" + q.Points.Key,
+		Title:    "Same nearest " + q.Points.Name,
+		Category: sharedModels.CategoryMatching,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 3, CardsToPick: 1},
+	}
+}
+
+func (q SameNearest) Ask(request Request) (Answer, error) {
+	data := request.Env.Data
+	index := q.Points.Index(data)
+
+	seekerNearest, _, seekerFound := index.Nearest(seekerPoint(request.Lobby))
+	hiderNearest, _, hiderFound := index.Nearest(hiderPoint(request.Lobby))
+	if !seekerFound || !hiderFound {
+		return Answer{}, sharedModels.ErrFeatureNotInRegion
+	}
+
+	var otherPoints []orb.Point
+	for _, point := range index.Points() {
+		if point.ElementID != seekerNearest.ElementID {
+			otherPoints = append(otherPoints, point.Location)
+		}
+	}
+	cell := geo.VoronoiCell(seekerNearest.Location, otherPoints, data.GameAreaBound)
+	if len(cell) < 4 {
+		log.Warn().Msg("voronoi cell of " + fmt.Sprint(seekerNearest.ElementID) + " is empty")
+		return Answer{}, sharedModels.ErrFeatureNotInRegion
+	}
+
+	nearestName := seekerNearest.Name
+	if nearestName == "" {
+		nearestName = "the one of the seeker"
+	}
+
+	log.Debug().Msg("seeker nearest " + q.Points.Name + " is " + fmt.Sprint(seekerNearest.ElementID) + " and hider nearest is " + fmt.Sprint(hiderNearest.ElementID))
+
+	// the hider is in the cell of the seekers nearest object exactly when it's the hiders nearest object too.
+	// everything outside of the game area is excluded anyway, so only the cell itself has to be clipped to it
+	if hiderNearest.ElementID == seekerNearest.ElementID {
+		return Answer{
+			Description: "Hiders nearest " + q.Points.Name + " is " + nearestName,
+			Exclusions:  []orb.Geometry{orb.Polygon{sharedModels.WideOutsideBound(), cell}},
+		}, nil
+	}
+	clippedCell, err := data.ClipToGameArea(orb.Polygon{cell})
+	if err != nil {
+		log.Err(err).Msg("failed clipping voronoi cell of " + fmt.Sprint(seekerNearest.ElementID) + " to the game area")
+		return Answer{}, err
+	}
+	return Answer{
+		Description: "Hiders nearest " + q.Points.Name + " isn't " + nearestName,
+		Exclusions:  []orb.Geometry{clippedCell},
+	}, nil
+}
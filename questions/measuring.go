@@ -0,0 +1,206 @@
+package questions
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb"
+	orbGeo "github.com/paulmach/orb/geo"
+	"github.com/paulmach/orb/planar"
+
+	"github.com/engelsjk/polygol"
+)
+
+// is the hider closer to the nearest object of a measuring category than the seeker?
+type CloserToObject struct {
+	// the key of the category in the region profile
+	Key      string
+	Category geo.MeasuringCategory
+}
+
+func (q CloserToObject) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "closerTo/" + q.Key,
+		Path:     "/closerTo/" + q.Key,
+		Title:    q.Category.Title,
+		Category: sharedModels.CategoryMeasuring,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 3, CardsToPick: 1},
+	}
+}
+
+func (q CloserToObject) Ask(request Request) (Answer, error) {
+	isCloser, distance, exclusion, err := closerOrFurtherFromObject(seekerPoint(request.Lobby), hiderPoint(request.Lobby), request.Env.Data.CategoryIndexes[q.Key])
+	if err != nil {
+		return Answer{}, err
+	}
+
+	var description string
+	if isCloser {
+		description = "Hider is closer than " + fmt.Sprint(math.Round(distance)) + "m to " + q.Category.ObjectName
+	} else {
+		description = "Hider is further than " + fmt.Sprint(math.Round(distance)) + "m from " + q.Category.ObjectName
+	}
+	return Answer{
+		Description: description,
+		Exclusions:  []orb.Geometry{exclusion},
+	}, nil
+}
+
+// is the hider closer to a line feature of the region than the seeker?
+type CloserToLine struct {
+	// the key of the line feature in the region profile
+	Key     string
+	Feature geo.LineFeature
+}
+
+func (q CloserToLine) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "closerToLine/" + q.Key,
+		Path:     "/closerToLine/" + q.Key,
+		Title:    q.Feature.Title,
+		Category: sharedModels.CategoryMeasuring,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 3, CardsToPick: 1},
+	}
+}
+
+func (q CloserToLine) Ask(request Request) (Answer, error) {
+	isCloser, distance, exclusion, err := closerOrFurtherFromOrbLine(seekerPoint(request.Lobby), hiderPoint(request.Lobby), request.Env.Data.LineFeatures[q.Key])
+	if err != nil {
+		return Answer{}, err
+	}
+
+	var description string
+	if isCloser {
+		description = "Hider is closer than " + fmt.Sprint(math.Round(distance)) + "m to " + q.Feature.LineName
+	} else {
+		description = "Hider is further than " + fmt.Sprint(math.Round(distance)) + "m from " + q.Feature.LineName
+	}
+	return Answer{
+		Description: description,
+		Exclusions:  []orb.Geometry{exclusion},
+	}, nil
+}
+
+// returns whether the hider is closer to the nearest object than the seeker, the distance of the seeker and the area the hider can't be in
+func closerOrFurtherFromObject(seekerPoint orb.Point, hiderPoint orb.Point, objectIndex *geo.PointIndex) (closer bool, distance float64, exclusion orb.Geometry, err error) {
+	_, seekerDistance, _ := objectIndex.Nearest(seekerPoint)
+	_, hiderDistance, _ := objectIndex.Nearest(hiderPoint)
+
+	var objectPoints []orb.Point
+	if objectIndex != nil {
+		for _, indexedPoint := range objectIndex.Points() {
+			objectPoints = append(objectPoints, indexedPoint.Location)
+		}
+	}
+
+	log.Debug().Msg(fmt.Sprint("seeker distance is ", seekerDistance, " and hider distance is ", hiderDistance))
+
+	var circleList orb.MultiPolygon
+	var circleGeomList []polygol.Geom
+
+	for index, point := range objectPoints {
+		if point == sharedModels.ZeroPoint {
+			log.Warn().Msg("point at index " + fmt.Sprint(index) + " of " + fmt.Sprint(len(objectPoints)) + " is zero")
+			continue
+		}
+		circle := helpers.NewCircle(point, seekerDistance)
+		circleList = append(circleList, orb.Polygon{circle})
+		circleGeomList = append(circleGeomList, helpers.G2p(orb.Polygon{circle}))
+	}
+
+	if len(circleGeomList) == 0 {
+		log.Warn().Msg("length of circle list is 0")
+		return false, 0.0, nil, sharedModels.ErrFeatureNotInRegion
+	}
+
+	// the seeker is closer to the object than the hider, so the hider is outside of all circles
+	if seekerDistance < hiderDistance {
+		return false, seekerDistance, circleList, nil
+	}
+
+	// the hider is closer to the object than the seeker, so the hider is inside one of the circles
+	outsideGeom := helpers.G2p(orb.Polygon{sharedModels.WideOutsideBound()})
+	diff, err := polygol.Difference(outsideGeom, circleGeomList...)
+	if err != nil {
+		log.Err(err).Msg("failed to get polygol difference")
+		return false, 0.0, nil, err
+	}
+	return true, seekerDistance, helpers.P2g(diff), nil
+}
+
+// returns whether the hider is closer to the lines than the seeker, the distance of the seeker and the area the hider can't be in
+func closerOrFurtherFromOrbLine(seekerPoint orb.Point, hiderPoint orb.Point, lineStrings []orb.LineString) (closer bool, distance float64, exclusion orb.Geometry, err error) {
+	if len(lineStrings) == 0 {
+		log.Warn().Msg("line feature isn't available in this region")
+		return false, 0.0, nil, sharedModels.ErrFeatureNotInRegion
+	}
+
+	seekerDistance := math.Inf(1)
+	hiderDistance := math.Inf(1)
+
+	for _, lineString := range lineStrings {
+		for _, point := range lineString {
+			currentSeekerDistance := orbGeo.DistanceHaversine(point, seekerPoint)
+			if currentSeekerDistance < seekerDistance && currentSeekerDistance != 0 {
+				seekerDistance = currentSeekerDistance
+			}
+			currentHiderDistance := orbGeo.DistanceHaversine(point, hiderPoint)
+			if currentHiderDistance < hiderDistance && currentHiderDistance != 0 {
+				hiderDistance = currentHiderDistance
+			}
+		}
+	}
+
+	log.Debug().Msg(fmt.Sprint("hider distance ", hiderDistance, " and seeker distance ", seekerDistance))
+
+	var multiPoly orb.MultiPolygon
+
+	for _, lineString := range lineStrings {
+		lineStringLength := len(lineString)
+		for index, point := range lineString {
+			if index < lineStringLength-1 {
+				nextPoint := lineString[index+1]
+
+				lineBearing := orbGeo.Bearing(point, nextPoint)
+				normalBearing := helpers.NormalizeBearing(lineBearing + 90)
+				antinormalBearing := helpers.NormalizeBearing(lineBearing - 90)
+
+				offsetPoint := orbGeo.PointAtBearingAndDistance(point, normalBearing, seekerDistance)
+				offsetNextPoint := orbGeo.PointAtBearingAndDistance(nextPoint, normalBearing, seekerDistance)
+				antiOffsetPoint := orbGeo.PointAtBearingAndDistance(point, antinormalBearing, seekerDistance)
+				antiOffsetNextPoint := orbGeo.PointAtBearingAndDistance(nextPoint, antinormalBearing, seekerDistance)
+				ring := orb.Ring{offsetPoint, offsetNextPoint, antiOffsetNextPoint, antiOffsetPoint, offsetPoint}
+
+				multiPoly = append(multiPoly, orb.Polygon{ring})
+			}
+			multiPoly = append(multiPoly, orb.Polygon{helpers.NewCircle(point, seekerDistance)})
+		}
+	}
+
+	geom := polygol.Geom(helpers.G2p(multiPoly))
+	union, err := polygol.Union(geom)
+	if err != nil {
+		log.Err(err).Msg("failed performing union operation")
+		return false, 0.0, nil, err
+	}
+
+	multiPoly = helpers.P2g(union)
+
+	if !planar.MultiPolygonContains(multiPoly, hiderPoint) {
+		return false, seekerDistance, multiPoly, nil
+	}
+
+	outsideGeom := helpers.G2p(orb.Polygon{sharedModels.WideOutsideBound()})
+	diff, err := polygol.Difference(outsideGeom, helpers.G2p(multiPoly))
+	if err != nil {
+		log.Err(err).Msg("failed to get polygol difference")
+		return false, 0.0, nil, err
+	}
+	return true, seekerDistance, helpers.P2g(diff), nil
+}
@@ -0,0 +1,269 @@
+package questions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+var ErrInvalidPhoto error = errors.New("Photo must be a JPEG, PNG or WebP image of at most 10MB")
+
+const maxPhotoSize = 10 << 20
+
+// file extensions of the image types the hider can upload
+var photoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// something the hider has to take a photo of
+type PhotoSubject struct {
+	Key string
+	// e.g. "a tree"
+	Name string
+	// how long the hider has to send the photo
+	AnswerTime time.Duration
+}
+
+var PhotoSubjects = []PhotoSubject{
+	{Key: "tree", Name: "a tree", AnswerTime: 10 * time.Minute},
+	{Key: "sky", Name: "the sky", AnswerTime: 10 * time.Minute},
+	{Key: "selfie", Name: "the hider", AnswerTime: 10 * time.Minute},
+	{Key: "widestStreet", Name: "the widest street", AnswerTime: 10 * time.Minute},
+	{Key: "tallestStructure", Name: "the tallest structure in sight", AnswerTime: 10 * time.Minute},
+	{Key: "platform", Name: "the train platform", AnswerTime: 20 * time.Minute},
+	{Key: "park", Name: "a park", AnswerTime: 20 * time.Minute},
+}
+
+// asks the hider for a photo, the hider answers by uploading it
+type Photo struct {
+	Subject PhotoSubject
+}
+
+func (q Photo) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "photo/" + q.Subject.Key,
+		Path:     "/photo/" + q.Subject.Key,
+		Title:    "Photo of " + q.Subject.Name,
+		Category: sharedModels.CategoryPhoto,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 1, CardsToPick: 1},
+	}
+}
+
+func (q Photo) AnswerTime() time.Duration {
+	return q.Subject.AnswerTime
+}
+
+func (q Photo) Ask(request Request) (Answer, error) {
+	return Answer{
+		Description: "Seeker asked for a photo of " + q.Subject.Name + ", the hider has " + fmt.Sprint(q.Subject.AnswerTime) + " to send it",
+	}, nil
+}
+
+// stores the photo of the hider for an open photo question and answers the question with it
+func photoUploadHandler(db *gorm.DB, env Env) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+		if !isLobby {
+			log.Debug().Msg(fmt.Sprint(lobby))
+			log.Warn().Msg("couldn't cast lobby value from context")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+		if !isUint {
+			log.Warn().Msg("failed to convert userID to uint in photo upload")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		if userID != lobby.HiderID {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(nil)
+			return
+		}
+
+		askedQuestionID, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(nil)
+			return
+		}
+		var askedQuestion models.AskedQuestion
+		result := db.Where("lobby_id = ? AND category = ?", lobby.ID, sharedModels.CategoryPhoto).First(&askedQuestion, askedQuestionID)
+		if result.Error != nil {
+			log.Warn().Msg("photo question " + fmt.Sprint(askedQuestionID) + " doesn't exist in lobby " + lobby.Token)
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(nil)
+			return
+		}
+
+		err = checkAnswerable(askedQuestion)
+		if err != nil {
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		photo, err := savePhoto(r, lobby, "question-"+fmt.Sprint(askedQuestion.ID), env.UploadDir)
+		if err != nil {
+			log.Err(err).Msg("failed saving photo for question " + fmt.Sprint(askedQuestion.ID))
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte(err.Error()))
+			return
+		}
+		result = db.Create(&photo)
+		if result.Error != nil {
+			log.Err(result.Error).Msg("failed creating photo")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		err = answerQuestion(db, env, &askedQuestion, "Hider sent the photo", photo.ID, w)
+		if err != nil {
+			log.Err(err).Msg("failed answering photo question " + fmt.Sprint(askedQuestion.ID))
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write(nil)
+	}
+}
+
+// writes the image in the request body to the upload directory
+func savePhoto(r *http.Request, lobby models.Lobby, name string, uploadDir string) (models.Photo, error) {
+	// one byte more than allowed so that too large photos can be detected
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPhotoSize+1))
+	if err != nil {
+		return models.Photo{}, err
+	}
+	if len(body) == 0 || len(body) > maxPhotoSize {
+		return models.Photo{}, ErrInvalidPhoto
+	}
+	// the content type of the request is set by the client, so the actual bytes get checked
+	contentType := http.DetectContentType(body)
+	extension, ok := photoExtensions[contentType]
+	if !ok {
+		log.Warn().Msg("rejected photo with content type " + contentType)
+		return models.Photo{}, ErrInvalidPhoto
+	}
+
+	err = os.MkdirAll(uploadDir, 0o755)
+	if err != nil {
+		return models.Photo{}, err
+	}
+	path := filepath.Join(uploadDir, lobby.Token+"-"+name+extension)
+	err = os.WriteFile(path, body, 0o644)
+	if err != nil {
+		return models.Photo{}, err
+	}
+
+	return models.Photo{
+		LobbyID:     lobby.ID,
+		Path:        path,
+		ContentType: contentType,
+	}, nil
+}
+
+// stores a photo of the hider that isn't the answer to a question, e.g. to pay the casting cost of a curse
+func newPhotoHandler(db *gorm.DB, env Env) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+		if !isLobby {
+			log.Debug().Msg(fmt.Sprint(lobby))
+			log.Warn().Msg("couldn't cast lobby value from context")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+		if !isUint {
+			log.Warn().Msg("failed to convert userID to uint in photo upload")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		if userID != lobby.HiderID {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(nil)
+			return
+		}
+
+		photo, err := savePhoto(r, lobby, helpers.RandomString(8, "abcdefghijklmnopqrstuvwxyz0123456789"), env.UploadDir)
+		if err != nil {
+			log.Err(err).Msg("failed saving photo")
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte(err.Error()))
+			return
+		}
+		result := db.Create(&photo)
+		if result.Error != nil {
+			log.Err(result.Error).Msg("failed creating photo")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		marshalledResponse, err := json.Marshal(sharedModels.PhotoUploadResponse{PhotoID: photo.ID})
+		if err != nil {
+			log.Err(err).Msg("failed to marshal photo upload response")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write(marshalledResponse)
+	}
+}
+
+// serves a photo of the lobby
+func photoHandler(db *gorm.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+		if !isLobby {
+			log.Debug().Msg(fmt.Sprint(lobby))
+			log.Warn().Msg("couldn't cast lobby value from context")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		photoID, err := strconv.ParseUint(chi.URLParam(r, "photoID"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write(nil)
+			return
+		}
+		var photo models.Photo
+		result := db.Where("lobby_id = ?", lobby.ID).First(&photo, photoID)
+		if result.Error != nil {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write(nil)
+			return
+		}
+
+		w.Header().Set("Content-Type", photo.ContentType)
+		http.ServeFile(w, r, photo.Path)
+	}
+}
@@ -0,0 +1,137 @@
+package questions
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/jkulzer/fib-server/curses"
+	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/scheduler"
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb"
+)
+
+// a question the seeker can ask. the registry takes care of the excluded area, the history and the card draw
+type Question interface {
+	Info() sharedModels.QuestionInfo
+	Ask(request Request) (Answer, error)
+}
+
+// everything a question needs besides the lobby
+type Env struct {
+	Data      geo.ProcessedData
+	Geocoder  geo.ReverseGeocoder
+	Scheduler *scheduler.Scheduler
+	// directory where uploaded photos get stored
+	UploadDir string
+}
+
+type Request struct {
+	// changes to the lobby get saved after the question was answered
+	Lobby *models.Lobby
+	Env   Env
+	// for URL parameters and the request body
+	HTTP *http.Request
+}
+
+type Answer struct {
+	// ends up in the history
+	Description string
+	// areas where the hider can't be, they get added to the excluded area of the lobby
+	Exclusions []orb.Geometry
+	// overrides the reward from the question info when set
+	Reward *sharedModels.QuestionReward
+}
+
+// a set of points questions can be asked about, e.g. the stations or the objects of a measuring category
+type PointCategory struct {
+	Key string
+	// e.g. "station"
+	Name  string
+	index func(data geo.ProcessedData) *geo.PointIndex
+}
+
+func StationPoints() PointCategory {
+	return PointCategory{
+		Key:  "station",
+		Name: "station",
+		index: func(data geo.ProcessedData) *geo.PointIndex {
+			return data.RailwayStationIndex
+		},
+	}
+}
+
+func MeasuringCategoryPoints(categoryKey string, category geo.MeasuringCategory) PointCategory {
+	name := category.Name
+	if name == "" {
+		name = categoryKey
+	}
+	return PointCategory{
+		Key:  categoryKey,
+		Name: name,
+		index: func(data geo.ProcessedData) *geo.PointIndex {
+			return data.CategoryIndexes[categoryKey]
+		},
+	}
+}
+
+func (c PointCategory) Index(data geo.ProcessedData) *geo.PointIndex {
+	return c.index(data)
+}
+
+var ErrThermometerNotStarted error = errors.New("Thermometer wasn't started")
+
+var ErrThermometerTooShort error = errors.New("Seeker hasn't moved the thermometer distance yet")
+
+// the status codes of the errors a question can return, everything else is an internal error
+var errorStatusCodes = []struct {
+	err        error
+	statusCode int
+}{
+	{sharedModels.ErrFeatureNotInRegion, http.StatusNotFound},
+	{sharedModels.ErrInvalidQuestionParameter, http.StatusBadRequest},
+	{geo.ErrNoAddressFound, http.StatusBadRequest},
+	{ErrThermometerNotStarted, http.StatusBadRequest},
+	{ErrThermometerTooShort, http.StatusMethodNotAllowed},
+	{scheduler.ErrInvalidPhaseTransition, http.StatusConflict},
+	{ErrQuestionPending, http.StatusConflict},
+	{ErrQuestionNotOpen, http.StatusConflict},
+	{ErrAnswerDeadlinePassed, http.StatusGone},
+	{ErrInvalidPhoto, http.StatusBadRequest},
+	{ErrPhotoAnswer, http.StatusBadRequest},
+	{ErrInvalidAnswer, http.StatusBadRequest},
+	{curses.ErrBlockedByCurse, http.StatusForbidden},
+}
+
+func statusCodeForError(err error) int {
+	for _, errorStatusCode := range errorStatusCodes {
+		if errors.Is(err, errorStatusCode.err) {
+			return errorStatusCode.statusCode
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+func seekerPoint(lobby *models.Lobby) orb.Point {
+	// yes, longitude comes first, look at https://pkg.go.dev/github.com/paulmach/orb#Point
+	return orb.Point{lobby.SeekerLon, lobby.SeekerLat}
+}
+
+func hiderPoint(lobby *models.Lobby) orb.Point {
+	return orb.Point{lobby.HiderLon, lobby.HiderLat}
+}
+
+func zoneCenterPoint(lobby *models.Lobby) orb.Point {
+	return orb.Point{lobby.ZoneCenterLon, lobby.ZoneCenterLat}
+}
+
+// e.g. 500m or 2km
+func radiusString(radius float64) string {
+	if radius < 1000 {
+		return fmt.Sprint(radius) + "m"
+	}
+	return fmt.Sprint(radius/1000) + "km"
+}
@@ -0,0 +1,99 @@
+package questions
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/scheduler"
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb"
+	orbGeo "github.com/paulmach/orb/geo"
+)
+
+// is the hider within the radius around the seeker?
+type Radar struct{}
+
+func (q Radar) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "radar",
+		Path:     "/radar/{radius}",
+		Title:    "Radar",
+		Category: sharedModels.CategoryRadar,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 2, CardsToPick: 1},
+	}
+}
+
+func (q Radar) Ask(request Request) (Answer, error) {
+	radius, err := strconv.ParseFloat(chi.URLParam(request.HTTP, "radius"), 64)
+	if err != nil || radius <= 0 {
+		log.Warn().Msg("failed parsing radar radius")
+		return Answer{}, sharedModels.ErrInvalidQuestionParameter
+	}
+
+	seeker := seekerPoint(request.Lobby)
+	seekerAddr, err := request.Env.Geocoder.ReverseGeocode(seeker)
+	if err != nil {
+		return Answer{}, err
+	}
+
+	radiusDistance := radiusString(radius)
+
+	if orbGeo.DistanceHaversine(hiderPoint(request.Lobby), seeker) < radius {
+		// it's a hit!
+		return Answer{
+			Description: "Hider is within " + radiusDistance + " of " + seekerAddr,
+			Exclusions:  []orb.Geometry{helpers.NewInverseCircle(seeker, radius)},
+		}, nil
+	}
+	// it's a miss!
+	return Answer{
+		Description: "Hider is not within " + radiusDistance + " of " + seekerAddr,
+		Exclusions:  []orb.Geometry{helpers.NewCircle(seeker, radius)},
+	}, nil
+}
+
+// is the seeker in the hiding zone? if so, the endgame starts
+type IsInHidingZone struct{}
+
+func (q IsInHidingZone) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "isInHidingZone",
+		Path:     "/isInHidingZone",
+		Title:    "Is in hiding zone",
+		Category: sharedModels.CategoryRadar,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 1, CardsToPick: 1},
+	}
+}
+
+func (q IsInHidingZone) Ask(request Request) (Answer, error) {
+	lobby := request.Lobby
+	seeker := seekerPoint(lobby)
+
+	seekerAddr, err := request.Env.Geocoder.ReverseGeocode(seeker)
+	if err != nil {
+		return Answer{}, err
+	}
+
+	if orbGeo.DistanceHaversine(zoneCenterPoint(lobby), seeker) > lobby.Settings.HidingZoneRadius {
+		return Answer{
+			Description: seekerAddr + " is not in hiding zone",
+		}, nil
+	}
+
+	err = request.Env.Scheduler.SetPhase(lobby, sharedModels.PhaseEndgame)
+	// the run end or a find can change the phase while the question is being answered
+	if err != nil && !errors.Is(err, scheduler.ErrPhaseChanged) {
+		log.Err(err).Msg("failed moving lobby to endgame")
+		return Answer{}, err
+	}
+	return Answer{
+		Description: seekerAddr + " is in hiding zone",
+		Exclusions:  []orb.Geometry{helpers.NewInverseCircle(seeker, lobby.Settings.HidingZoneRadius*2)},
+	}, nil
+}
@@ -0,0 +1,380 @@
+package questions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math/rand"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/curses"
+	"github.com/jkulzer/fib-server/events"
+	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb/geojson"
+)
+
+// keeps the questions of the server and mounts their routes
+type Registry struct {
+	questions []Question
+	// old paths of questions, they are mounted but not listed
+	aliases map[string]Question
+}
+
+func NewRegistry(questions ...Question) *Registry {
+	registry := &Registry{
+		aliases: make(map[string]Question),
+	}
+	for _, question := range questions {
+		registry.Register(question)
+	}
+	return registry
+}
+
+// the questions every region has plus the measuring categories of the region profile
+func DefaultRegistry(profile geo.RegionProfile) *Registry {
+	registry := NewRegistry(
+		TrainService{},
+		Radar{},
+		Thermometer{},
+		// the IDs stay the ones from Berlin because the clients use them
+		SameArea{ID: "sameBezirk", LevelName: profile.AreaLevelName(geo.DistrictLevel), Level: geo.DistrictLevel},
+		SameArea{ID: "sameOrtsteil", LevelName: profile.AreaLevelName(geo.SubdistrictLevel), Level: geo.SubdistrictLevel},
+		AreaLastLetter{ID: "ortsteilLastLetter", LevelName: profile.AreaLevelName(geo.SubdistrictLevel), Level: geo.SubdistrictLevel},
+		IsInHidingZone{},
+		SameNearest{Points: StationPoints()},
+		Tentacle{Points: StationPoints()},
+	)
+
+	for _, subject := range PhotoSubjects {
+		registry.Register(Photo{Subject: subject})
+	}
+	for _, hiderQuestion := range HiderQuestions {
+		registry.Register(hiderQuestion)
+	}
+
+	lineKeys := slices.Sorted(maps.Keys(profile.LineFeatures))
+	for _, lineKey := range lineKeys {
+		registry.Register(CloserToLine{
+			Key:     lineKey,
+			Feature: profile.LineFeatures[lineKey],
+		})
+	}
+	categoryKeys := slices.Sorted(maps.Keys(profile.MeasuringCategories))
+	for _, categoryKey := range categoryKeys {
+		registry.Register(CloserToObject{
+			Key:      categoryKey,
+			Category: profile.MeasuringCategories[categoryKey],
+		})
+		categoryPoints := MeasuringCategoryPoints(categoryKey, profile.MeasuringCategories[categoryKey])
+		registry.Register(SameNearest{Points: categoryPoints})
+		registry.Register(Tentacle{Points: categoryPoints})
+	}
+	// clients from before the configurable categories still use these
+	if category, ok := profile.MeasuringCategories["mcdonalds"]; ok {
+		registry.RegisterAlias("/closerToMcDonalds", CloserToObject{Key: "mcdonalds", Category: category})
+	}
+	if category, ok := profile.MeasuringCategories["ikea"]; ok {
+		registry.RegisterAlias("/closerToIkea", CloserToObject{Key: "ikea", Category: category})
+	}
+	if lineFeature, ok := profile.LineFeatures["spree"]; ok {
+		registry.RegisterAlias("/closerToSpree", CloserToLine{Key: "spree", Feature: lineFeature})
+	}
+
+	return registry
+}
+
+func (reg *Registry) Register(question Question) {
+	reg.questions = append(reg.questions, question)
+}
+
+// mounts the question under another path without listing it
+func (reg *Registry) RegisterAlias(path string, question Question) {
+	reg.aliases[path] = question
+}
+
+func (reg *Registry) Infos() []sharedModels.QuestionInfo {
+	var infos []sharedModels.QuestionInfo
+	for _, question := range reg.questions {
+		infos = append(infos, question.Info())
+	}
+	return infos
+}
+
+// picks a random other question the hider answers from the same category as the question with the id
+func (reg *Registry) RandomReplacement(questionID string) (sharedModels.QuestionInfo, bool) {
+	var category sharedModels.QuestionCategory
+	found := false
+	for _, question := range reg.questions {
+		if question.Info().ID == questionID {
+			category = question.Info().Category
+			found = true
+		}
+	}
+	if !found {
+		return sharedModels.QuestionInfo{}, false
+	}
+
+	var candidates []sharedModels.QuestionInfo
+	for _, question := range reg.questions {
+		info := question.Info()
+		if _, ok := question.(HiderAnswered); !ok || info.Category != category || info.ID == questionID {
+			continue
+		}
+		candidates = append(candidates, info)
+	}
+	if len(candidates) == 0 {
+		return sharedModels.QuestionInfo{}, false
+	}
+	return candidates[rand.Intn(len(candidates))], true
+}
+
+// mounts the question list, a POST route for every question, the asked questions and the answer and photo routes
+func (reg *Registry) Mount(r chi.Router, db *gorm.DB, env Env) {
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
+		response := sharedModels.QuestionListResponse{
+			Questions: reg.Infos(),
+		}
+		marshalledResponse, err := json.Marshal(response)
+		if err != nil {
+			log.Err(err).Msg("failed to marshal question list response")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(marshalledResponse)
+	})
+	for _, question := range reg.questions {
+		r.Post(question.Info().Path, askHandler(question, db, env))
+	}
+	for path, question := range reg.aliases {
+		r.Post(path, askHandler(question, db, env))
+	}
+	r.Get("/asked", askedQuestionsHandler(db))
+	r.Post("/{id}/answer", answerHandler(db, env))
+	r.Post("/{id}/photo", photoUploadHandler(db, env))
+	r.Post("/photos", newPhotoHandler(db, env))
+	r.Get("/photos/{photoID}", photoHandler(db))
+}
+
+func askHandler(question Question, db *gorm.DB, env Env) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+		if !isLobby {
+			log.Debug().Msg(fmt.Sprint(lobby))
+			log.Warn().Msg("couldn't cast lobby value from context")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+		if !isUint {
+			log.Warn().Msg("failed to convert userID to uint in question ask")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		// only the seeker asks questions
+		if userID != lobby.SeekerID {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write(nil)
+			return
+		}
+
+		info := question.Info()
+
+		_, isPending, err := OpenQuestion(db, lobby.ID)
+		if err != nil {
+			log.Err(err).Msg("failed loading open question of lobby " + lobby.Token)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		if isPending {
+			log.Warn().Msg("lobby " + lobby.Token + " still has an open question")
+			w.WriteHeader(statusCodeForError(ErrQuestionPending))
+			w.Write([]byte(ErrQuestionPending.Error()))
+			return
+		}
+
+		err = curses.CheckQuestion(curses.QuestionAsk{
+			Lobby: lobby,
+			Info:  info,
+			Data:  env.Data,
+		})
+		if err != nil {
+			w.WriteHeader(statusCodeForError(err))
+			w.Write([]byte(err.Error()))
+			return
+		}
+
+		answer, err := question.Ask(Request{
+			Lobby: &lobby,
+			Env:   env,
+			HTTP:  r,
+		})
+		if err != nil {
+			log.Err(err).Msg("failed asking question " + info.ID)
+			w.WriteHeader(statusCodeForError(err))
+			w.Write(nil)
+			return
+		}
+
+		reward := info.Reward
+		if answer.Reward != nil {
+			reward = *answer.Reward
+		}
+		askedQuestion := models.AskedQuestion{
+			LobbyID:     lobby.ID,
+			QuestionID:  info.ID,
+			Title:       info.Title,
+			Category:    info.Category,
+			State:       sharedModels.QuestionAnswered,
+			Deadline:    time.Now(),
+			Answer:      answer.Description,
+			CardsToDraw: reward.CardsToDraw,
+			CardsToPick: reward.CardsToPick,
+		}
+
+		// the hider answers later, until then only the question goes into the history
+		if hiderAnswered, ok := question.(HiderAnswered); ok {
+			askedQuestion.State = sharedModels.QuestionAsked
+			askedQuestion.Deadline = time.Now().Add(hiderAnswered.AnswerTime())
+			askedQuestion.Answer = ""
+			var deadline models.ScheduledEvent
+			err = events.Transaction(db, func(tx *gorm.DB) error {
+				var err error
+				deadline, err = askPending(tx, env, &askedQuestion, answer.Description)
+				return err
+			})
+			if err != nil {
+				if errors.Is(err, ErrQuestionPending) {
+					log.Warn().Msg("lobby " + lobby.Token + " still has an open question")
+					w.WriteHeader(statusCodeForError(err))
+					w.Write([]byte(err.Error()))
+					return
+				}
+				log.Err(err).Msg("failed asking question " + info.ID)
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write(nil)
+				return
+			}
+			env.Scheduler.Arm(deadline)
+			writeAskedQuestion(w, http.StatusCreated, askedQuestion)
+			return
+		}
+
+		fc, err := helpers.FCFromDB(lobby)
+		if err != nil {
+			log.Err(err).Msg("failed to get FC from DB while asking question " + info.ID)
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		for _, exclusion := range answer.Exclusions {
+			fc.Append(geojson.NewFeature(exclusion))
+		}
+		lobby, err = helpers.SaveFC(lobby, fc)
+		if err != nil {
+			log.Err(err).Msg("failed to save FC to lobby struct")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		result := db.Save(&lobby)
+		if result.Error != nil {
+			log.Err(result.Error).Msg("failed saving lobby")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		helpers.PublishMapUpdate(lobby)
+
+		result = db.Create(&askedQuestion)
+		if result.Error != nil {
+			log.Err(result.Error).Msg("failed creating asked question")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		historyItem := models.HistoryInDB{
+			LobbyID:     lobby.ID,
+			Title:       info.Title,
+			Description: answer.Description,
+		}
+		result = db.Create(&historyItem)
+		if result.Error != nil {
+			log.Err(result.Error).Msg("failed creating history item")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+
+		if reward.CardsToDraw > 0 {
+			err = helpers.CreateCardDraw(db, reward.CardsToDraw, reward.CardsToPick, lobby.ID, w)
+			if err != nil {
+				log.Err(err).Msg("failed creating card draw")
+				w.WriteHeader(http.StatusInternalServerError)
+				w.Write(nil)
+				return
+			}
+		}
+
+		writeAskedQuestion(w, http.StatusOK, askedQuestion)
+	}
+}
+
+// stores the question that waits for the hider together with its deadline and history item.
+// the question is inserted before checking for other open ones, so that of two concurrent asks the later one
+// sees the first one and gets rolled back
+func askPending(tx *gorm.DB, env Env, askedQuestion *models.AskedQuestion, description string) (models.ScheduledEvent, error) {
+	result := tx.Create(askedQuestion)
+	if result.Error != nil {
+		return models.ScheduledEvent{}, result.Error
+	}
+	var openQuestions int64
+	result = tx.Model(&models.AskedQuestion{}).Where("lobby_id = ? AND state = ?", askedQuestion.LobbyID, sharedModels.QuestionAsked).Count(&openQuestions)
+	if result.Error != nil {
+		return models.ScheduledEvent{}, result.Error
+	}
+	if openQuestions > 1 {
+		return models.ScheduledEvent{}, ErrQuestionPending
+	}
+
+	deadline, err := env.Scheduler.ScheduleIn(tx, askedQuestion.LobbyID, models.EventAnswerDeadline, askedQuestion.ID, askedQuestion.Deadline)
+	if err != nil {
+		return models.ScheduledEvent{}, err
+	}
+	historyItem := models.HistoryInDB{
+		LobbyID:     askedQuestion.LobbyID,
+		Title:       askedQuestion.Title,
+		Description: description,
+	}
+	return deadline, tx.Create(&historyItem).Error
+}
+
+func writeAskedQuestion(w http.ResponseWriter, statusCode int, askedQuestion models.AskedQuestion) {
+	marshalledResponse, err := json.Marshal(askedQuestion.DTO())
+	if err != nil {
+		log.Err(err).Msg("failed to marshal asked question")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write(nil)
+		return
+	}
+	w.WriteHeader(statusCode)
+	w.Write(marshalledResponse)
+}
@@ -0,0 +1,99 @@
+package questions
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb"
+	orbGeo "github.com/paulmach/orb/geo"
+)
+
+// every candidate gets a cell against all other candidates, so the radius has to stay small
+const maxTentacleRadius = 2000.0
+
+// of all objects of a category within the radius around the seeker, which one is the closest to the hider?
+type Tentacle struct {
+	Points PointCategory
+}
+
+func (q Tentacle) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "tentacle/" + q.Points.Key,
+		Path:     "/tentacle/" + q.Points.Key + "/{radius}",
+		Title:    "Tentacle: closest " + q.Points.Name,
+		Category: sharedModels.CategoryTentacle,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 4, CardsToPick: 2},
+	}
+}
+
+func (q Tentacle) Ask(request Request) (Answer, error) {
+	radius, err := strconv.ParseFloat(chi.URLParam(request.HTTP, "radius"), 64)
+	if err != nil || radius <= 0 {
+		log.Warn().Msg("failed parsing tentacle radius")
+		return Answer{}, sharedModels.ErrInvalidQuestionParameter
+	}
+	if radius > maxTentacleRadius {
+		log.Warn().Msg("tentacle radius " + fmt.Sprint(radius) + "m is larger than " + radiusString(maxTentacleRadius))
+		return Answer{}, sharedModels.ErrInvalidQuestionParameter
+	}
+
+	seeker := seekerPoint(request.Lobby)
+	hider := hiderPoint(request.Lobby)
+
+	candidates := q.Points.Index(request.Env.Data).WithinRadius(seeker, radius)
+	if len(candidates) == 0 {
+		log.Warn().Msg("no " + q.Points.Name + " within " + fmt.Sprint(radius) + "m of the seeker")
+		return Answer{}, sharedModels.ErrFeatureNotInRegion
+	}
+
+	if orbGeo.DistanceHaversine(hider, seeker) > radius {
+		return Answer{
+			Description: "None of the " + q.Points.Name + " candidates, hider is not within " + radiusString(radius) + " of the seeker",
+			Exclusions:  []orb.Geometry{helpers.NewCircle(seeker, radius)},
+		}, nil
+	}
+
+	hiderClosest := candidates[0]
+	for _, candidate := range candidates[1:] {
+		if orbGeo.DistanceHaversine(hider, candidate.Location) < orbGeo.DistanceHaversine(hider, hiderClosest.Location) {
+			hiderClosest = candidate
+		}
+	}
+
+	// the hider is within the radius and in the cell of its closest candidate
+	answer := Answer{
+		Exclusions: []orb.Geometry{helpers.NewInverseCircle(seeker, radius)},
+	}
+	cellBound := orbGeo.NewBoundAroundPoint(seeker, radius)
+	for _, candidate := range candidates {
+		if candidate.ElementID == hiderClosest.ElementID {
+			continue
+		}
+		var otherPoints []orb.Point
+		for _, other := range candidates {
+			if other.ElementID != candidate.ElementID {
+				otherPoints = append(otherPoints, other.Location)
+			}
+		}
+		cell := geo.VoronoiCell(candidate.Location, otherPoints, cellBound)
+		if len(cell) < 4 {
+			continue
+		}
+		answer.Exclusions = append(answer.Exclusions, orb.Polygon{cell})
+	}
+
+	closestName := hiderClosest.Name
+	if closestName == "" {
+		closestName = "the " + q.Points.Name + " at " + fmt.Sprint(hiderClosest.Location.Lat()) + ", " + fmt.Sprint(hiderClosest.Location.Lon())
+	}
+	answer.Description = "Of the " + q.Points.Name + " candidates within " + radiusString(radius) + " of the seeker, " + closestName + " is closest to the hider"
+	return answer, nil
+}
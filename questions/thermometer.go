@@ -0,0 +1,89 @@
+package questions
+
+import (
+	"fmt"
+
+	"github.com/jkulzer/fib-server/sharedModels"
+
+	"github.com/paulmach/orb"
+	orbGeo "github.com/paulmach/orb/geo"
+)
+
+// ends the thermometer the seeker started at /thermometer/start: is the hider closer to the start or the end?
+type Thermometer struct{}
+
+func (q Thermometer) Info() sharedModels.QuestionInfo {
+	return sharedModels.QuestionInfo{
+		ID:       "thermometer",
+		Path:     "/thermometer/end",
+		Title:    "Thermometer",
+		Category: sharedModels.CategoryThermometer,
+		Reward:   sharedModels.QuestionReward{CardsToDraw: 2, CardsToPick: 1},
+	}
+}
+
+func (q Thermometer) Ask(request Request) (Answer, error) {
+	lobby := request.Lobby
+	if lobby.ThermometerDistance == 0 {
+		return Answer{}, ErrThermometerNotStarted
+	}
+
+	hider := hiderPoint(lobby)
+	seeker := seekerPoint(lobby)
+	thermometerStartPoint := orb.Point{lobby.ThermometerStartLon, lobby.ThermometerStartLat}
+
+	if orbGeo.DistanceHaversine(thermometerStartPoint, seeker) < lobby.ThermometerDistance {
+		return Answer{}, ErrThermometerTooShort
+	}
+
+	thermometerBearing := orbGeo.Bearing(thermometerStartPoint, seeker)
+	var leftBearing float64
+	var rightBearing float64
+
+	if thermometerBearing < -90 {
+		leftBearing = thermometerBearing + 90
+		rightBearing = thermometerBearing + 270
+	} else if thermometerBearing > 90 {
+		leftBearing = thermometerBearing - 270
+		rightBearing = thermometerBearing - 90
+	} else {
+		leftBearing = thermometerBearing + 90
+		rightBearing = thermometerBearing - 90
+	}
+
+	thermometerStartAddr, err := request.Env.Geocoder.ReverseGeocode(thermometerStartPoint)
+	if err != nil {
+		return Answer{}, err
+	}
+	thermometerEndAddr, err := request.Env.Geocoder.ReverseGeocode(seeker)
+	if err != nil {
+		return Answer{}, err
+	}
+
+	var description string
+	// if thermometer is hotter
+	if orbGeo.DistanceHaversine(seeker, hider) < orbGeo.DistanceHaversine(thermometerStartPoint, hider) {
+		if thermometerBearing > 0 {
+			thermometerBearing = thermometerBearing - 180
+		} else {
+			thermometerBearing = thermometerBearing + 180
+		}
+		description = "Hider is closer to " + fmt.Sprint(thermometerEndAddr) + " then to " + fmt.Sprint(thermometerStartAddr)
+	} else {
+		description = "Hider is closer to " + fmt.Sprint(thermometerStartAddr) + " then to " + fmt.Sprint(thermometerEndAddr)
+	}
+
+	boxFrontLeft := orbGeo.PointAtBearingAndDistance(orbGeo.PointAtBearingAndDistance(seeker, thermometerBearing, 30000), leftBearing, 30000)
+	boxFrontRight := orbGeo.PointAtBearingAndDistance(orbGeo.PointAtBearingAndDistance(seeker, thermometerBearing, 30000), rightBearing, 30000)
+	boxLeft := orbGeo.PointAtBearingAndDistance(seeker, leftBearing, 30000)
+	boxRight := orbGeo.PointAtBearingAndDistance(seeker, rightBearing, 30000)
+
+	boxPolygon := orb.Polygon{orb.Ring{boxFrontLeft, boxFrontRight, boxRight, boxLeft, boxFrontLeft}}
+
+	lobby.ThermometerDistance = 0
+
+	return Answer{
+		Description: description,
+		Exclusions:  []orb.Geometry{boxPolygon},
+	}, nil
+}
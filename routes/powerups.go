@@ -0,0 +1,225 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/events"
+	"github.com/jkulzer/fib-server/geo"
+	"github.com/jkulzer/fib-server/helpers"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/questions"
+	"github.com/jkulzer/fib-server/scheduler"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+// how long the hider has to get to the new hiding zone after playing a move card
+const zoneMoveTime = 30 * time.Minute
+
+var powerUpStatusCodes = map[error]int{
+	sharedModels.ErrNoOpenQuestion:         http.StatusConflict,
+	sharedModels.ErrNoRandomQuestion:       http.StatusConflict,
+	sharedModels.ErrInvalidDuplicateTarget: http.StatusBadRequest,
+	sharedModels.ErrInvalidZoneCenter:      http.StatusBadRequest,
+}
+
+func powerUpStatusCode(err error) int {
+	for knownErr, statusCode := range powerUpStatusCodes {
+		if errors.Is(err, knownErr) {
+			return statusCode
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// everything playing a power-up needs besides the lobby
+type powerUpEnv struct {
+	sched    *scheduler.Scheduler
+	registry *questions.Registry
+	data     geo.ProcessedData
+}
+
+// plays a power-up card from the hand of the hider. the power-up, its casting cost and its effect
+// either all happen or none of them does
+func playPowerUp(w http.ResponseWriter, db *gorm.DB, env powerUpEnv, lobby models.Lobby, powerUp models.Card, request sharedModels.PlayCardRequest, discardedCards []models.Card) {
+	lobby.HiderDeck = slices.DeleteFunc(lobby.HiderDeck, func(card models.Card) bool {
+		if card.ID == powerUp.ID {
+			return true
+		}
+		return slices.ContainsFunc(discardedCards, func(discardedCard models.Card) bool {
+			return discardedCard.ID == card.ID
+		})
+	})
+
+	// the answer deadline of a vetoed question gets cancelled once the veto is stored
+	var vetoedQuestionID uint
+	err := events.Transaction(db, func(tx *gorm.DB) error {
+		for _, discardedCard := range discardedCards {
+			result := tx.Delete(&discardedCard)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		result := tx.Delete(&powerUp)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		var description string
+		var err error
+		switch powerUp.Type {
+		case sharedModels.Discard1Draw2Card:
+			description, err = drawCards(tx, lobby, 2)
+		case sharedModels.Discard2Draw3Card:
+			description, err = drawCards(tx, lobby, 3)
+		case sharedModels.VetoCard:
+			description, vetoedQuestionID, err = vetoQuestion(tx, lobby)
+		case sharedModels.RandomizeCard:
+			description, err = randomizeQuestion(tx, lobby, env.registry)
+		case sharedModels.DuplicateCard:
+			description, err = duplicateCard(tx, lobby, powerUp, request.TargetCardID)
+		case sharedModels.MoveCard:
+			description, err = moveZone(&lobby, request, env.data)
+		default:
+			err = fmt.Errorf("card type %d isn't a power-up", powerUp.Type)
+		}
+		if err != nil {
+			return err
+		}
+
+		result = tx.Save(&lobby)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if len(discardedCards) == 1 {
+			description += " and discarded 1 card"
+		} else if len(discardedCards) > 1 {
+			description += " and discarded " + fmt.Sprint(len(discardedCards)) + " cards"
+		}
+		historyItem := models.HistoryInDB{
+			LobbyID:     lobby.ID,
+			Title:       powerUp.Title,
+			Description: description,
+		}
+		return tx.Create(&historyItem).Error
+	})
+	if err != nil {
+		statusCode := powerUpStatusCode(err)
+		if statusCode == http.StatusInternalServerError {
+			log.Err(err).Msg("failed playing power-up " + fmt.Sprint(powerUp.ID))
+			w.WriteHeader(statusCode)
+			w.Write(nil)
+			return
+		}
+		log.Warn().Msg("rejected power-up " + fmt.Sprint(powerUp.ID) + ": " + err.Error())
+		w.WriteHeader(statusCode)
+		w.Write([]byte(err.Error()))
+		return
+	}
+
+	if vetoedQuestionID != 0 {
+		err = env.sched.Cancel(lobby.ID, models.EventAnswerDeadline, vetoedQuestionID)
+		if err != nil {
+			log.Err(err).Msg("failed cancelling answer deadline of question " + fmt.Sprint(vetoedQuestionID))
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(nil)
+}
+
+// lets the hider draw the cards and keep all of them
+func drawCards(tx *gorm.DB, lobby models.Lobby, amount uint) (string, error) {
+	err := helpers.CreateCardDraw(tx, amount, amount, lobby.ID, nil)
+	if err != nil {
+		return "", err
+	}
+	return "Hider draws " + fmt.Sprint(amount) + " cards", nil
+}
+
+// the hider doesn't have to answer the open question and doesn't get its reward
+func vetoQuestion(tx *gorm.DB, lobby models.Lobby) (string, uint, error) {
+	askedQuestion, isOpen, err := questions.OpenQuestion(tx, lobby.ID)
+	if err != nil {
+		return "", 0, err
+	}
+	if !isOpen {
+		return "", 0, sharedModels.ErrNoOpenQuestion
+	}
+	askedQuestion.State = sharedModels.QuestionVetoed
+	result := tx.Save(&askedQuestion)
+	if result.Error != nil {
+		return "", 0, result.Error
+	}
+	return "Hider vetoed the question \"" + askedQuestion.Title + "\"", askedQuestion.ID, nil
+}
+
+// replaces the open question with another one of the same category, the deadline stays the same
+func randomizeQuestion(tx *gorm.DB, lobby models.Lobby, registry *questions.Registry) (string, error) {
+	askedQuestion, isOpen, err := questions.OpenQuestion(tx, lobby.ID)
+	if err != nil {
+		return "", err
+	}
+	if !isOpen {
+		return "", sharedModels.ErrNoOpenQuestion
+	}
+	replacement, found := registry.RandomReplacement(askedQuestion.QuestionID)
+	if !found {
+		return "", sharedModels.ErrNoRandomQuestion
+	}
+
+	oldTitle := askedQuestion.Title
+	askedQuestion.QuestionID = replacement.ID
+	askedQuestion.Title = replacement.Title
+	askedQuestion.CardsToDraw = replacement.Reward.CardsToDraw
+	askedQuestion.CardsToPick = replacement.Reward.CardsToPick
+	result := tx.Save(&askedQuestion)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return "Hider replaced the question \"" + oldTitle + "\" with \"" + replacement.Title + "\"", nil
+}
+
+// puts a copy of another card of the hand into the hand
+func duplicateCard(tx *gorm.DB, lobby models.Lobby, duplicate models.Card, targetCardID uint) (string, error) {
+	targetIndex := slices.IndexFunc(lobby.HiderDeck, func(card models.Card) bool {
+		return card.ID == targetCardID
+	})
+	if targetIndex == -1 || targetCardID == duplicate.ID {
+		return "", sharedModels.ErrInvalidDuplicateTarget
+	}
+	target := lobby.HiderDeck[targetIndex]
+	if target.Type == sharedModels.DuplicateCard {
+		return "", sharedModels.ErrInvalidDuplicateTarget
+	}
+
+	cardCopy := target
+	cardCopy.Model = gorm.Model{}
+	cardCopy.HiderDeckLobbyID = lobby.ID
+	result := tx.Create(&cardCopy)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	return "Hider duplicated \"" + target.Title + "\"", nil
+}
+
+// moves the hiding zone, the hider gets some time to get there before the zone is enforced again.
+// the caller saves the lobby
+func moveZone(lobby *models.Lobby, request sharedModels.PlayCardRequest, data geo.ProcessedData) (string, error) {
+	if !geo.PointIsValidZoneCenter(request.Location, lobby.Settings.HidingZoneRadius, data) {
+		return "", sharedModels.ErrInvalidZoneCenter
+	}
+	// yes, longitude comes first, look at https://pkg.go.dev/github.com/paulmach/orb#Point
+	lobby.ZoneCenterLat = request.Location[1]
+	lobby.ZoneCenterLon = request.Location[0]
+	lobby.ZoneMoveDeadline = time.Now().Add(zoneMoveTime)
+	return "Hider moved the hiding zone and has " + zoneMoveTime.String() + " to get there", nil
+}
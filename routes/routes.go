@@ -4,6 +4,7 @@ import (
 	"gorm.io/gorm"
 
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"math/rand"
@@ -13,9 +14,15 @@ import (
 	"time"
 
 	"github.com/jkulzer/fib-server/controllers"
+	"github.com/jkulzer/fib-server/curses"
+	"github.com/jkulzer/fib-server/decks"
+	"github.com/jkulzer/fib-server/events"
 	"github.com/jkulzer/fib-server/geo"
 	"github.com/jkulzer/fib-server/helpers"
 	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/questions"
+	"github.com/jkulzer/fib-server/scheduler"
+	"github.com/jkulzer/fib-server/scoring"
 	"github.com/jkulzer/fib-server/sharedModels"
 
 	"github.com/jkulzer/osm"
@@ -23,17 +30,18 @@ import (
 	"github.com/paulmach/orb"
 	orbGeo "github.com/paulmach/orb/geo"
 	"github.com/paulmach/orb/geojson"
-	"github.com/paulmach/orb/planar"
 	"github.com/paulmach/orb/simplify"
 
-	"github.com/engelsjk/polygol"
-
 	chi "github.com/go-chi/chi/v5"
 
+	"github.com/gorilla/websocket"
+
 	"github.com/rs/zerolog/log"
 )
 
-func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
+func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData, sched *scheduler.Scheduler, geocoder geo.ReverseGeocoder, uploadDir string, packs decks.Packs) {
+	questionRegistry := questions.DefaultRegistry(processedData.Profile)
+
 	r.Post("/register", func(w http.ResponseWriter, r *http.Request) {
 		body, err := helpers.ReadHttpResponse(r.Body)
 		if err != nil {
@@ -115,6 +123,17 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 		w.Write(jsonResponse)
 		return
 	})
+	r.Get("/decks", func(w http.ResponseWriter, r *http.Request) {
+		marshalledJson, err := json.Marshal(sharedModels.DeckPackListResponse{Packs: packs.Infos()})
+		if err != nil {
+			log.Err(err).Msg("failed to marshal deck pack list")
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write(nil)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write(marshalledJson)
+	})
 	r.Route("/lobby", func(r chi.Router) {
 		r.Use(AuthMiddleware(db))
 		r.Post("/create", func(w http.ResponseWriter, r *http.Request) {
@@ -132,6 +151,7 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 				db.Where("creator_id = ?", userID).Delete(&models.Lobby{})
 				lobby.Token = lobbyToken
 				lobby.Phase = sharedModels.PhaseBeforeStart
+				lobby.Settings = sharedModels.DefaultGameSettings()
 				// lobby.CreatorID = userID
 				log.Info().Msg("user ID " + fmt.Sprint(userID))
 				lobby.CreatorID = userID
@@ -295,6 +315,96 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 				w.WriteHeader(http.StatusOK)
 				w.Write(marshalledJson)
 			})
+			r.Get("/settings", func(w http.ResponseWriter, r *http.Request) {
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+
+				marshalledJson, err := json.Marshal(lobby.Settings)
+				if err != nil {
+					log.Err(err).Msg("failed to marshal lobby settings")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				w.WriteHeader(http.StatusOK)
+				w.Write(marshalledJson)
+			})
+			r.Put("/settings", func(w http.ResponseWriter, r *http.Request) {
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+				if isUint == false {
+					log.Warn().Msg("failed to convert userID to uint in settings update")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+
+				if userID != lobby.CreatorID {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(nil)
+					return
+				}
+				if lobby.Phase != sharedModels.PhaseBeforeStart {
+					w.WriteHeader(http.StatusConflict)
+					w.Write([]byte(sharedModels.ErrSettingsLocked.Error()))
+					return
+				}
+
+				body, err := helpers.ReadHttpResponse(r.Body)
+				if err != nil {
+					log.Err(err).Msg("failed to read http request of body " + fmt.Sprint(err))
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(nil)
+					return
+				}
+				var settings sharedModels.GameSettings
+				err = json.Unmarshal(body, &settings)
+				if err != nil {
+					log.Warn().Msg("failed to parse json of settings update")
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(nil)
+					return
+				}
+
+				err = settings.Validate()
+				if err != nil {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(err.Error()))
+					return
+				}
+				// clients that don't know about deck packs keep the default one
+				if settings.DeckPack == "" {
+					settings.DeckPack = sharedModels.DefaultDeckPack
+				}
+				if _, ok := packs[settings.DeckPack]; !ok {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(sharedModels.ErrUnknownDeckPack.Error()))
+					return
+				}
+
+				lobby.Settings = settings
+				result := db.Save(&lobby)
+				if result.Error != nil {
+					log.Err(result.Error).Msg("failed to save lobby settings")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				w.Write(nil)
+			})
 			r.Get("/readiness", func(w http.ResponseWriter, r *http.Request) {
 				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
 				if !isLobby {
@@ -347,27 +457,13 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					w.Write(nil)
 				}
 
-				var zoneCenter orb.Point
-				zoneCenter[1] = lobby.ZoneCenterLat
-				zoneCenter[0] = lobby.ZoneCenterLon
-
-				switch userID {
-				case lobby.SeekerID:
-					// yes, longitude comes first, look at https://pkg.go.dev/github.com/paulmach/orb#Point
-					lobby.SeekerLat = locationRequest.Location[1]
-					lobby.SeekerLon = locationRequest.Location[0]
-				case lobby.HiderID:
-					if lobby.Phase == sharedModels.PhaseLocationNarrowing || lobby.Phase == sharedModels.PhaseEndgame {
-						if orbGeo.DistanceHaversine(locationRequest.Location, zoneCenter) > sharedModels.HidingZoneRadius {
-
-							w.WriteHeader(http.StatusConflict)
-							w.Write(nil)
-							return
-						}
-					}
-					// yes, longitude comes first, look at https://pkg.go.dev/github.com/paulmach/orb#Point
-					lobby.HiderLat = locationRequest.Location[1]
-					lobby.HiderLon = locationRequest.Location[0]
+				err = applyLocation(&lobby, userID, locationRequest.Location)
+				switch err {
+				case nil:
+				case sharedModels.ErrHiderLocationNotInZone:
+					w.WriteHeader(http.StatusConflict)
+					w.Write(nil)
+					return
 				default:
 					w.WriteHeader(http.StatusForbidden)
 					w.Write(nil)
@@ -382,9 +478,91 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					return
 				}
 
+				err = curses.OnLocationUpdate(db, lobby, userID)
+				if err != nil {
+					log.Err(err).Msg("failed running curse location hooks")
+				}
+
 				w.WriteHeader(http.StatusOK)
 				w.Write(nil)
 			})
+			r.Get("/locationStream", func(w http.ResponseWriter, r *http.Request) {
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+				if isUint == false {
+					log.Warn().Msg("failed to convert userID to uint in location stream")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				if userID != lobby.HiderID && userID != lobby.SeekerID {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(nil)
+					return
+				}
+
+				conn, err := websocketUpgrader.Upgrade(w, r, nil)
+				if err != nil {
+					// the upgrader already wrote the error response
+					log.Err(err).Msg("failed upgrading location stream to websocket")
+					return
+				}
+				defer conn.Close()
+
+				lobbyID := lobby.ID
+				for {
+					var locationRequest sharedModels.LocationRequest
+					err = conn.ReadJSON(&locationRequest)
+					if err != nil {
+						if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+							log.Err(err).Msg("failed reading from location stream")
+						}
+						return
+					}
+
+					// only loads the columns needed for the location rules instead of the whole lobby
+					var currentLobby models.Lobby
+					result := db.Select("id", "phase", "hider_id", "seeker_id", "zone_center_lat", "zone_center_lon", "zone_move_deadline", "settings_hiding_zone_radius").First(&currentLobby, lobbyID)
+					if result.Error != nil {
+						log.Err(result.Error).Msg("failed loading lobby for location stream")
+						return
+					}
+
+					var response sharedModels.LocationStreamResponse
+					err = applyLocation(&currentLobby, userID, locationRequest.Location)
+					switch err {
+					case nil:
+						// the location of the other player isn't loaded, so only the columns of the caller get written
+						result = db.Model(&models.Lobby{}).Where("id = ?", lobbyID).Updates(locationColumns(currentLobby, userID))
+						if result.Error != nil {
+							log.Err(result.Error).Msg("failed to save location to DB")
+							return
+						}
+						err = curses.OnLocationUpdate(db, currentLobby, userID)
+						if err != nil {
+							log.Err(err).Msg("failed running curse location hooks")
+						}
+						response.Accepted = true
+					case sharedModels.ErrHiderLocationNotInZone:
+						response.Warning = err.Error()
+					default:
+						log.Warn().Msg("user " + fmt.Sprint(userID) + " lost their role while streaming locations")
+						return
+					}
+
+					err = conn.WriteJSON(response)
+					if err != nil {
+						log.Err(err).Msg("failed writing to location stream")
+						return
+					}
+				}
+			})
 			r.Put("/saveHidingZone", func(w http.ResponseWriter, r *http.Request) {
 				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
 				if !isLobby {
@@ -419,7 +597,7 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					return
 				}
 
-				isValidPoint := geo.PointIsValidZoneCenter(locationRequest.Location, processedData)
+				isValidPoint := geo.PointIsValidZoneCenter(locationRequest.Location, lobby.Settings.HidingZoneRadius, processedData)
 
 				if !isValidPoint {
 					w.WriteHeader(http.StatusBadRequest)
@@ -494,33 +672,15 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					return
 				}
 
-				if lobby.HiderReady && lobby.SeekerReady {
-					lobby.Phase = sharedModels.PhaseRun
-					lobby.RunStartTime = time.Now()
-					result = db.Save(&lobby)
-					if result.Error != nil {
+				if lobby.HiderReady && lobby.SeekerReady && lobby.Phase == sharedModels.PhaseBeforeStart {
+					err = sched.SetPhase(&lobby, sharedModels.PhaseRun)
+					// the other player's request can start the run at the same time
+					if err != nil && !errors.Is(err, scheduler.ErrPhaseChanged) {
+						log.Err(err).Msg("failed to start run for lobby " + lobby.Token)
 						w.WriteHeader(http.StatusInternalServerError)
 						w.Write(nil)
 						return
 					}
-					go func() {
-						runTimer := time.NewTimer(sharedModels.RunDuration)
-						lobbyToken := lobby.Token
-
-						<-runTimer.C
-						log.Info().Msg("Hiding Time Finished")
-						var lobby models.Lobby
-						result := db.Where("token = ?", lobbyToken).First(&lobby)
-						if result.Error != nil {
-							log.Err(err).Msg("failed to save finished hiding time to DB for lobby " + lobbyToken)
-							return
-						}
-						lobby.Phase = sharedModels.PhaseLocationNarrowing
-						result = db.Save(&lobby)
-						if result.Error != nil {
-							log.Err(result.Error).Msg("")
-						}
-					}()
 				}
 
 				w.WriteHeader(http.StatusOK)
@@ -747,12 +907,21 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					return
 				}
 
+				pack, ok := packs[lobby.Settings.DeckPack]
+				if !ok {
+					log.Error().Msg("deck pack " + lobby.Settings.DeckPack + " of lobby " + lobby.Token + " isn't loaded")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write([]byte(sharedModels.ErrUnknownDeckPack.Error()))
+					return
+				}
+
+				// drawn cards leave the remaining cards, the copies in the draw are new cards
+				var drawnCards []models.Card
 				for i := uint(1); i <= draw.CardsToDraw; i++ {
 					log.Debug().Msg("getting random card: i=" + fmt.Sprint(i) + " limit is  " + fmt.Sprint(draw.CardsToDraw))
 
 					if len(lobby.RemainingCards) < 1 {
-						cardList := sharedModels.GetCardList()
-						for _, card := range cardList {
+						for _, card := range pack.Cards {
 							lobby.RemainingCards = append(lobby.RemainingCards, helpers.ExternalToInternalCard(card))
 						}
 					}
@@ -767,9 +936,18 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 						ExpirationDuration: generatedCard.ExpirationDuration,
 						ActivationTime:     generatedCard.ActivationTime,
 						BonusTime:          generatedCard.BonusTime,
+						PenaltyTime:        generatedCard.PenaltyTime,
+						Effect:             generatedCard.Effect,
+						Resolvable:         generatedCard.Resolvable,
+
+						CastingCostDescription: generatedCard.CastingCostDescription,
+						CastingCost:            generatedCard.CastingCost,
 					})
 
-					lobby.RemainingCards = slices.Delete(lobby.RemainingCards, randomCardIndex, randomCardIndex)
+					if generatedCard.ID != 0 {
+						drawnCards = append(drawnCards, generatedCard)
+					}
+					lobby.RemainingCards = slices.Delete(lobby.RemainingCards, randomCardIndex, randomCardIndex+1)
 				}
 				lobby.CurrentDraw.ToPick = draw.CardsToPick
 
@@ -781,6 +959,16 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					return
 				}
 
+				for _, drawnCard := range drawnCards {
+					result = db.Delete(&drawnCard)
+					if result.Error != nil {
+						log.Err(result.Error).Msg("failed deleting drawn card from the remaining cards")
+						w.WriteHeader(http.StatusInternalServerError)
+						w.Write(nil)
+						return
+					}
+				}
+
 				result = db.Delete(&draw)
 				if result.Error != nil {
 					log.Err(result.Error).Msg("failed deleting draw after drawing cards")
@@ -815,16 +1003,7 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 
 				var currentDrawCardList []sharedModels.Card
 				for _, dbCard := range lobby.CurrentDraw.Cards {
-					currentDrawCardList = append(currentDrawCardList, sharedModels.Card{
-						IDInDB:             dbCard.ID,
-						Title:              dbCard.Title,
-						Description:        dbCard.Description,
-						Type:               dbCard.Type,
-						ExpirationDuration: dbCard.ExpirationDuration,
-						ActivationTime:     dbCard.ActivationTime,
-						BonusTime:          dbCard.BonusTime,
-					},
-					)
+					currentDrawCardList = append(currentDrawCardList, dbCard.DTO())
 				}
 
 				dtoCurrentDraw := sharedModels.CurrentDraw{
@@ -880,7 +1059,7 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 				db.Preload("HiderDeck").Find(&lobby)
 				db.Preload("CurrentDraw.Cards").Find(&lobby)
 
-				if len(pickedCards.CardIDList)+len(lobby.HiderDeck) > sharedModels.MaxHandSize {
+				if len(pickedCards.CardIDList)+len(lobby.HiderDeck) > lobby.Settings.MaxHandSize {
 					log.Err(err).Msg("can't pick cards, hand would overflow")
 					w.WriteHeader(http.StatusConflict)
 					w.Write(nil)
@@ -1040,47 +1219,128 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					return
 				}
 
-				var cardToPlay models.Card
-
-				result := db.Find(&cardToPlay, cardID)
-				if result.Error != nil {
-					log.Err(result.Error).Msg("failed to find card to play in DB")
-					w.WriteHeader(http.StatusInternalServerError)
+				if userID != lobby.HiderID {
+					w.WriteHeader(http.StatusForbidden)
 					w.Write(nil)
 					return
 				}
 
-				cardToPlay.HiderDeckLobbyID = 0
+				// the body is optional, only some cards need parameters or a casting cost
+				var playCardRequest sharedModels.PlayCardRequest
+				body, err := helpers.ReadHttpResponse(r.Body)
+				if err != nil {
+					log.Err(err).Msg("failed to read http request of body " + fmt.Sprint(err))
+				}
+				if len(body) > 0 {
+					err = json.Unmarshal(body, &playCardRequest)
+					if err != nil {
+						log.Warn().Msg("failed to parse json of play card request")
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write(nil)
+						return
+					}
+				}
+
+				cardIndex := slices.IndexFunc(lobby.HiderDeck, func(card models.Card) bool {
+					return card.ID == uint(cardID)
+				})
+				if cardIndex == -1 {
+					log.Warn().Msg("card " + fmt.Sprint(cardID) + " isn't in the hand of the hider")
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(nil)
+					return
+				}
+				cardToPlay := lobby.HiderDeck[cardIndex]
 
 				switch cardToPlay.Type {
 				case sharedModels.CurseCard:
-					cardToPlay.ActivationTime = time.Now()
-					lobby.PlayedCurseList = append(lobby.PlayedCurseList, cardToPlay)
+					err = curses.Prepare(&cardToPlay, lobby, playCardRequest.Parameters)
+					if err != nil {
+						log.Warn().Msg("rejected parameters of curse " + fmt.Sprint(cardToPlay.ID) + ": " + err.Error())
+						w.WriteHeader(http.StatusBadRequest)
+						w.Write([]byte(err.Error()))
+						return
+					}
 				case sharedModels.TimebonusCard:
-					log.Err(result.Error).Msg("can't play a timebonus card")
+					log.Warn().Msg("can't play a timebonus card")
 					w.WriteHeader(http.StatusBadRequest)
 					w.Write(nil)
 					return
-					// case sharedModels.Discard1Draw2Card:
-					// case sharedModels.Discard2Draw3Card:
 				}
 
-				result = db.Delete(&cardToPlay)
-				if result.Error != nil {
-					log.Err(result.Error).Msg("failed saving card")
-					w.WriteHeader(http.StatusInternalServerError)
-					w.Write(nil)
+				discardedCards, err := curses.CheckPayment(db, lobby, cardToPlay, playCardRequest.Payment)
+				if err != nil {
+					log.Warn().Msg("rejected payment of card " + fmt.Sprint(cardToPlay.ID) + ": " + err.Error())
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(err.Error()))
 					return
 				}
 
-				result = db.Save(&lobby)
-				if result.Error != nil {
-					log.Err(result.Error).Msg("failed saving lobby")
+				if cardToPlay.Type != sharedModels.CurseCard {
+					playPowerUp(w, db, powerUpEnv{
+						sched:    sched,
+						registry: questionRegistry,
+						data:     processedData,
+					}, lobby, cardToPlay, playCardRequest, discardedCards)
+					return
+				}
+
+				// the curse moves from the hand to the played curses instead of getting deleted, so that its state can change later
+				lobby.HiderDeck = slices.DeleteFunc(lobby.HiderDeck, func(card models.Card) bool {
+					if card.ID == cardToPlay.ID {
+						return true
+					}
+					return slices.ContainsFunc(discardedCards, func(discardedCard models.Card) bool {
+						return discardedCard.ID == card.ID
+					})
+				})
+				cardToPlay.HiderDeckLobbyID = 0
+				cardToPlay.PlayedCurseID = lobby.ID
+				cardToPlay.ActivationTime = time.Now()
+				cardToPlay.PhotoID = playCardRequest.Payment.PhotoID
+				lobby.PlayedCurseList = append(lobby.PlayedCurseList, cardToPlay)
+
+				description := "Hider played the curse"
+				if cardToPlay.ExpirationDuration > 0 {
+					description += " for " + cardToPlay.ExpirationDuration.String()
+				}
+				if len(discardedCards) == 1 {
+					description += " and discarded 1 card"
+				} else if len(discardedCards) > 1 {
+					description += " and discarded " + fmt.Sprint(len(discardedCards)) + " cards"
+				}
+
+				// the casting cost only gets charged if the curse gets played and the other way round
+				err = events.Transaction(db, func(tx *gorm.DB) error {
+					for _, discardedCard := range discardedCards {
+						result := tx.Delete(&discardedCard)
+						if result.Error != nil {
+							return result.Error
+						}
+					}
+					result := tx.Save(&lobby)
+					if result.Error != nil {
+						return result.Error
+					}
+					return curses.SetState(tx, &cardToPlay, sharedModels.CurseActive, description)
+				})
+				if err != nil {
+					log.Err(err).Msg("failed playing curse")
 					w.WriteHeader(http.StatusInternalServerError)
 					w.Write(nil)
 					return
 				}
 
+				if cardToPlay.ExpirationDuration > 0 {
+					err = sched.Schedule(lobby.ID, models.EventCurseExpiry, cardToPlay.ID, cardToPlay.ActivationTime.Add(cardToPlay.ExpirationDuration))
+					if err != nil {
+						log.Err(err).Msg("failed scheduling curse expiry")
+						w.WriteHeader(http.StatusInternalServerError)
+						w.Write(nil)
+						return
+					}
+				}
+
 				w.WriteHeader(http.StatusOK)
 				w.Write(nil)
 			})
@@ -1118,11 +1378,11 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 				w.WriteHeader(http.StatusOK)
 				w.Write(marshaledCurses)
 			})
-			r.Get("/history", func(w http.ResponseWriter, r *http.Request) {
+			r.Post("/curses/{cardID}/penalty", func(w http.ResponseWriter, r *http.Request) {
 				userID, isUint := r.Context().Value(models.UserIDKey).(uint)
 				if !isUint {
 					log.Debug().Msg(fmt.Sprint(userID))
-					log.Warn().Msg("failed to convert userID to uint in role selection")
+					log.Warn().Msg("failed to convert userID to uint in curse penalty")
 					w.WriteHeader(http.StatusInternalServerError)
 					w.Write(nil)
 					return
@@ -1135,969 +1395,338 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 					w.Write(nil)
 					return
 				}
-				var historyList []sharedModels.HistoryItem
-				for _, dbItem := range lobby.History {
-					historyList = append(historyList, sharedModels.HistoryItem{
-						Title:       dbItem.Title,
-						Description: dbItem.Description,
-					})
+				// the seeker reports failing the curse, otherwise the hider could collect the penalty at any time
+				if userID != lobby.SeekerID {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(nil)
+					return
+				}
+				// the score is final once the game is finished
+				if lobby.Phase == sharedModels.PhaseFinished {
+					w.WriteHeader(http.StatusConflict)
+					w.Write(nil)
+					return
+				}
+				cardIDString := chi.URLParam(r, "cardID")
+				cardID, err := strconv.ParseUint(cardIDString, 10, 64)
+				if err != nil {
+					log.Err(err).Msg("failed parsing card id")
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(nil)
+					return
 				}
 
-				marshaledHistory, err := json.Marshal(historyList)
+				curseIndex := slices.IndexFunc(lobby.PlayedCurseList, func(curse models.Card) bool {
+					return curse.ID == uint(cardID)
+				})
+				if curseIndex == -1 {
+					w.WriteHeader(http.StatusNotFound)
+					w.Write(nil)
+					return
+				}
+				curse := &lobby.PlayedCurseList[curseIndex]
+				if curse.PenaltyTime == 0 || curse.PenaltyApplied || !curse.IsActive(time.Now()) {
+					w.WriteHeader(http.StatusConflict)
+					w.Write(nil)
+					return
+				}
+				curse.PenaltyApplied = true
+
+				// failing the curse clears it
+				description := "Seeker failed the curse, hider gets an extra " + curse.PenaltyTime.String()
+				err = curses.SetState(db, curse, sharedModels.CurseResolved, description)
 				if err != nil {
-					log.Err(err).Msg("failed marshaling history json")
+					log.Err(err).Msg("failed saving curse penalty")
 					w.WriteHeader(http.StatusInternalServerError)
 					w.Write(nil)
+					return
+				}
+				err = sched.Cancel(lobby.ID, models.EventCurseExpiry, curse.ID)
+				if err != nil {
+					log.Err(err).Msg("failed cancelling curse expiry")
 				}
 
 				w.WriteHeader(http.StatusOK)
-				w.Write(marshaledHistory)
+				w.Write(nil)
 			})
-			r.Route("/questions", func(r chi.Router) {
-				r.Use(AuthMiddleware(db))
-				r.Get("/closeRoutes", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					var seekerPoint orb.Point
-					seekerPoint[0] = lobby.SeekerLon
-					seekerPoint[1] = lobby.SeekerLat
-
-					closeRoutes := make(map[osm.RelationID]*osm.Relation)
-
-				routeIteration:
-					for routeID, route := range processedData.AllRailRoutes {
-					memberIteration:
-						for _, member := range route.Members {
-							if member.Type == "way" {
-								memberWayID, err := member.ElementID().WayID()
-								if err != nil {
-									log.Err(err).Msg("element id: " + fmt.Sprint(member.ElementID()))
-									continue memberIteration
-								}
-								memberWay := processedData.Ways[memberWayID]
-								lineString := geo.LineStringFromWay(memberWay, processedData.Nodes)
-								for _, routePoint := range lineString {
-									if orbGeo.DistanceHaversine(routePoint, seekerPoint) < 300 {
-										closeRoutes[routeID] = route
-										continue routeIteration
-									}
-								}
-							}
-						}
-					}
-					response := sharedModels.RouteProximityResponse{}
-					for _, route := range closeRoutes {
-						routeItem := sharedModels.RouteDetails{
-							Name:    route.Tags.Find("ref"),
-							RouteID: route.ID,
-						}
-						response.Routes = append(response.Routes, routeItem)
-					}
-
-					marshaledReponse, err := json.Marshal(response)
-					if err != nil {
-						log.Err(err).Msg("failed to marshal route proximity reponse")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					w.WriteHeader(http.StatusOK)
-					w.Write(marshaledReponse)
-				})
-				r.Post("/trainService", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					body, err := helpers.ReadHttpResponse(r.Body)
-					if err != nil {
-						log.Err(err).Msg("failed to read http request of body " + fmt.Sprint(err))
-					}
-
-					var trainServiceRequest sharedModels.TrainServiceRequest
-					err = json.Unmarshal(body, &trainServiceRequest)
-					if err != nil {
-						log.Warn().Msg("failed to parse json of train service request")
-						w.WriteHeader(http.StatusBadRequest)
-						w.Write(nil)
-					}
-
-					var zoneCenter orb.Point
-					zoneCenter[1] = lobby.ZoneCenterLat
-					zoneCenter[0] = lobby.ZoneCenterLon
-
-					route := processedData.Relations[trainServiceRequest.RouteID]
-
-					fc, err := helpers.FCFromDB(lobby)
-
-					if err != nil {
-						log.Err(err).Msg("")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					isOnLine := false
-
-				memberIteration:
-					for _, member := range route.Members {
-						if member.Type == "node" {
-							memberNodeID, err := member.ElementID().NodeID()
-							if err != nil {
-								log.Err(err).Msg("element id: " + fmt.Sprint(member.ElementID()))
-								continue memberIteration
-							}
-							memberNode := processedData.Nodes[memberNodeID]
-							if memberNode == nil {
-								continue
-							}
-							if memberNode.Tags.Find("railway") != "stop" {
-								continue memberIteration
-							}
-							stopPositionPoint := helpers.NodeToPoint(*memberNode)
-							if orbGeo.DistanceHaversine(stopPositionPoint, zoneCenter) <= sharedModels.HidingZoneRadius {
-								log.Debug().Msg("hider is at stop " + memberNode.Tags.Find("name") + " with ID " + fmt.Sprint(memberNode.ElementID()))
-								isOnLine = true
-							}
-						}
-					}
-
-					var circleGeomList []polygol.Geom
-					var circleList []orb.Ring
-
-				secondMemberIteration:
-					for _, member := range route.Members {
-						if member.Type == "node" {
-							memberNodeID, err := member.ElementID().NodeID()
-							if err != nil {
-								log.Err(err).Msg("element id: " + fmt.Sprint(member.ElementID()))
-								continue secondMemberIteration
-							}
-							memberNode := processedData.Nodes[memberNodeID]
-							if memberNode == nil {
-								continue secondMemberIteration
-							}
-							if memberNode.Tags.Find("railway") != "stop" {
-								continue secondMemberIteration
-							}
-							stopPositionPoint := helpers.NodeToPoint(*memberNode)
-							var circle orb.Ring
-							if isOnLine {
-								circle = helpers.NewCircle(stopPositionPoint, sharedModels.HidingZoneRadius*2)
-							} else {
-								circle = helpers.NewCircle(stopPositionPoint, sharedModels.HidingZoneRadius)
-							}
-
-							circleList = append(circleList, circle)
-							geomCircle := helpers.G2p(orb.Polygon{circle})
-							circleGeomList = append(circleGeomList, geomCircle)
-						}
-					}
-
-					var description string
-
-					if isOnLine {
-						outsideGeom := helpers.G2p(orb.Polygon{sharedModels.WideOutsideBound()})
-						diff, err := polygol.Difference(outsideGeom, circleGeomList...)
-						if err != nil {
-							log.Err(err).Msg("failed to make union of circles for train service question")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-						exclusionPolygons := helpers.P2g(diff)
-
-						for index := range exclusionPolygons {
-							if index < len(exclusionPolygons)-1 {
-								exclusionPolygons[index] = append(exclusionPolygons[index], sharedModels.WideOutsideBound())
-							}
-						}
-						fc.Append(geojson.NewFeature(exclusionPolygons))
-						description = route.Tags.Find("name") + " stops in the hiding zone"
-					} else {
-						for _, circle := range circleList {
-							fc.Append(geojson.NewFeature(circle))
-						}
-						description = route.Tags.Find("name") + " doesn't stop in the hiding zone"
-					}
-
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Train Service",
-						Description: description,
-					}
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					err = helpers.CreateCardDraw(db, 3, 1, lobby.ID, w)
-					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					err = helpers.FCToDB(db, lobby, fc)
-					if err != nil {
-						log.Err(err).Msg("failed to save fc to db during train service question request")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					w.WriteHeader(http.StatusOK)
+			r.Post("/curses/{cardID}/resolve", func(w http.ResponseWriter, r *http.Request) {
+				userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+				if !isUint {
+					log.Debug().Msg(fmt.Sprint(userID))
+					log.Warn().Msg("failed to convert userID to uint in curse resolution")
+					w.WriteHeader(http.StatusInternalServerError)
 					w.Write(nil)
-				})
-				r.Post("/radar/{radius}", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					radarRadiusString := chi.URLParam(r, "radius")
-
-					radius, err := strconv.ParseFloat(radarRadiusString, 64)
-					if err != nil {
-						log.Err(err).Msg("failed parsing radar radius")
-						w.WriteHeader(http.StatusBadRequest)
-						w.Write(nil)
-						return
-					}
-
-					var hiderPoint orb.Point
-					hiderPoint[0] = lobby.HiderLon
-					hiderPoint[1] = lobby.HiderLat
-					var seekerPoint orb.Point
-					seekerPoint[0] = lobby.SeekerLon
-					seekerPoint[1] = lobby.SeekerLat
-
-					distanceSeekerHider := orbGeo.DistanceHaversine(hiderPoint, seekerPoint)
-					seekerAddr, err := getClosestAdressString(seekerPoint)
-					if err != nil {
-						log.Err(err).Msg("failed getting seeker address")
-						w.WriteHeader(http.StatusBadRequest)
-						w.Write(nil)
-						return
-					}
-					log.Debug().Msg("got seeker address")
-
-					var radiusDistance string
-					if radius < 1000 {
-						radiusDistance = fmt.Sprint(radius) + "m"
-					} else {
-						radiusDistance = fmt.Sprint(radius/1000) + "km"
-					}
-					fc, err := helpers.FCFromDB(lobby)
-					if err != nil {
-						log.Err(err).Msg("")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					if distanceSeekerHider < radius {
-						// it's a hit!
-						log.Debug().Msg("it's a match")
-						inverseCircle := helpers.NewInverseCircle(seekerPoint, radius)
-						log.Debug().Msg("creating circle with radius " + fmt.Sprint(radius))
-						inverseCircleFeature := geojson.NewFeature(inverseCircle)
-						fc.Append(inverseCircleFeature)
-						historyItem := models.HistoryInDB{
-							LobbyID:     lobby.ID,
-							Title:       "Radar",
-							Description: "Hider is within " + radiusDistance + " of " + seekerAddr,
-						}
-						result := db.Create(&historyItem)
-						if result.Error != nil {
-							log.Err(err).Msg("failed creating history item")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-					} else {
-						// it's a miss!
-						log.Debug().Msg("it's not a match")
-						circle := helpers.NewCircle(seekerPoint, radius)
-						log.Debug().Msg("creating circle with radius " + fmt.Sprint(radius))
-						log.Debug().Msg("circle: " + fmt.Sprint(circle))
-						circleFeature := geojson.NewFeature(circle)
-						fc = fc.Append(circleFeature)
-						historyItem := models.HistoryInDB{
-							LobbyID:     lobby.ID,
-							Title:       "Radar",
-							Description: "Hider is not within " + radiusDistance + " of " + seekerAddr,
-						}
-
-						result := db.Create(&historyItem)
-						if result.Error != nil {
-							log.Err(err).Msg("failed creating history item")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-					}
-					err = helpers.FCToDB(db, lobby, fc)
-					if err != nil {
-						log.Err(err).Msg("")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					err = helpers.CreateCardDraw(db, 2, 1, lobby.ID, w)
-					if err != nil {
-						log.Err(err).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-					w.WriteHeader(http.StatusOK)
+					return
+				}
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Debug().Msg(fmt.Sprint(lobby))
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
 					w.Write(nil)
-				})
-				r.Route("/thermometer", func(r chi.Router) {
-					r.Post("/start", func(w http.ResponseWriter, r *http.Request) {
-						lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-						if !isLobby {
-							log.Debug().Msg(fmt.Sprint(lobby))
-							log.Warn().Msg("couldn't cast lobby value from context")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-
-						body, err := helpers.ReadHttpResponse(r.Body)
-						if err != nil {
-							log.Err(err).Msg("failed to read http request of body " + fmt.Sprint(err))
-						}
-
-						if lobby.ThermometerDistance != 0 {
-							log.Info().Msg("thermometer already started! can't start another one")
-							w.WriteHeader(http.StatusConflict)
-							w.Write(nil)
-							return
-						}
-
-						var thermometerRequest sharedModels.ThermometerRequest
-						err = json.Unmarshal(body, &thermometerRequest)
-						if err != nil {
-							log.Err(err).Msg("")
-							w.WriteHeader(http.StatusBadRequest)
-							w.Write(nil)
-							return
-						}
-						lobby.ThermometerStartLon = lobby.SeekerLon
-						lobby.ThermometerStartLat = lobby.SeekerLat
-						lobby.ThermometerDistance = thermometerRequest.Distance
-
-						result := db.Save(&lobby)
-						if result.Error != nil {
-							log.Err(result.Error).Msg("")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-						w.WriteHeader(http.StatusOK)
-						w.Write(nil)
-					})
-					r.Post("/end", func(w http.ResponseWriter, r *http.Request) {
-						lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-						if !isLobby {
-							log.Debug().Msg(fmt.Sprint(lobby))
-							log.Warn().Msg("couldn't cast lobby value from context")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-
-						if lobby.ThermometerDistance == 0 {
-							w.WriteHeader(http.StatusBadRequest)
-							w.Write(nil)
-							return
-						}
-
-						var hiderPoint orb.Point
-						hiderPoint[0] = lobby.HiderLon
-						hiderPoint[1] = lobby.HiderLat
-
-						var seekerPoint orb.Point
-						seekerPoint[0] = lobby.SeekerLon
-						seekerPoint[1] = lobby.SeekerLat
-
-						var thermometerStartPoint orb.Point
-						thermometerStartPoint[0] = lobby.ThermometerStartLon
-						thermometerStartPoint[1] = lobby.ThermometerStartLat
-
-						if orbGeo.DistanceHaversine(thermometerStartPoint, seekerPoint) < lobby.ThermometerDistance {
-							w.WriteHeader(http.StatusMethodNotAllowed)
-							w.Write(nil)
-							return
-						}
-
-						var description string
-
-						thermometerBearing := orbGeo.Bearing(thermometerStartPoint, seekerPoint)
-						var leftBearing float64
-						var rightBearing float64
-
-						if thermometerBearing < -90 {
-							leftBearing = thermometerBearing + 90
-							rightBearing = thermometerBearing + 270
-						} else if thermometerBearing > 90 {
-							leftBearing = thermometerBearing - 270
-							rightBearing = thermometerBearing - 90
-						} else {
-							leftBearing = thermometerBearing + 90
-							rightBearing = thermometerBearing - 90
-						}
-
-						thermometerStartAddr, err := getClosestAdressString(thermometerStartPoint)
-						if err != nil {
-							log.Err(err).Msg("failed getting address string")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-						thermometerEndAddr, err := getClosestAdressString(seekerPoint)
-						if err != nil {
-							log.Err(err).Msg("failed getting address string")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-
-						// if thermometer is hotter
-						if orbGeo.DistanceHaversine(seekerPoint, hiderPoint) < orbGeo.DistanceHaversine(thermometerStartPoint, hiderPoint) {
-							if thermometerBearing > 0 {
-								thermometerBearing = thermometerBearing - 180
-							} else {
-								thermometerBearing = thermometerBearing + 180
-							}
-							description = "Hider is closer to " + fmt.Sprint(thermometerEndAddr) + " then to " + fmt.Sprint(thermometerStartAddr)
-						} else {
-							description = "Hider is closer to " + fmt.Sprint(thermometerStartAddr) + " then to " + fmt.Sprint(thermometerEndAddr)
-						}
-
-						boxFrontLeft := orbGeo.PointAtBearingAndDistance(orbGeo.PointAtBearingAndDistance(seekerPoint, thermometerBearing, 30000), leftBearing, 30000)
-						boxFrontRight := orbGeo.PointAtBearingAndDistance(orbGeo.PointAtBearingAndDistance(seekerPoint, thermometerBearing, 30000), rightBearing, 30000)
-						boxLeft := orbGeo.PointAtBearingAndDistance(seekerPoint, leftBearing, 30000)
-						boxRight := orbGeo.PointAtBearingAndDistance(seekerPoint, rightBearing, 30000)
-
-						boxPolygon := orb.Polygon{orb.Ring{boxFrontLeft, boxFrontRight, boxRight, boxLeft, boxFrontLeft}}
-
-						fc, err := helpers.FCFromDB(lobby)
-						if err != nil {
-							log.Err(err).Msg("")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-						fc.Append(geojson.NewFeature(boxPolygon))
-						lobby.ThermometerDistance = 0
-
-						historyItem := models.HistoryInDB{
-							LobbyID:     lobby.ID,
-							Title:       "Thermometer",
-							Description: description,
-						}
-						result := db.Create(&historyItem)
-						if result.Error != nil {
-							log.Err(err).Msg("failed creating history item")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-
-						err = helpers.CreateCardDraw(db, 2, 1, lobby.ID, w)
-						if err != nil {
-							log.Err(result.Error).Msg("failed creating card draw")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
-
-						err = helpers.FCToDB(db, lobby, fc)
-						if err != nil {
-							log.Err(err).Msg("")
-							w.WriteHeader(http.StatusInternalServerError)
-							w.Write(nil)
-							return
-						}
+					return
+				}
+				if userID != lobby.SeekerID {
+					w.WriteHeader(http.StatusForbidden)
+					w.Write(nil)
+					return
+				}
+				cardIDString := chi.URLParam(r, "cardID")
+				cardID, err := strconv.ParseUint(cardIDString, 10, 64)
+				if err != nil {
+					log.Err(err).Msg("failed parsing card id")
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write(nil)
+					return
+				}
 
-						w.WriteHeader(http.StatusOK)
-						w.Write(nil)
-					})
+				curseIndex := slices.IndexFunc(lobby.PlayedCurseList, func(curse models.Card) bool {
+					return curse.ID == uint(cardID)
 				})
-				r.Post("/sameBezirk", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					fc, err := helpers.FCFromDB(lobby)
-					if err != nil {
-						log.Err(err).Msg("failed to get FC from DB while asking same bezirk question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					var zoneCenter orb.Point
-					zoneCenter[1] = lobby.ZoneCenterLat
-					zoneCenter[0] = lobby.ZoneCenterLon
-
-					var seekerPoint orb.Point
-					seekerPoint[1] = lobby.SeekerLat
-					seekerPoint[0] = lobby.SeekerLon
-
-					var hiderPoint orb.Point
-					hiderPoint[1] = lobby.HiderLat
-					hiderPoint[0] = lobby.HiderLon
-
-					polygonMap := make(map[string]orb.MultiPolygon)
-
-					for _, relation := range processedData.Bezirke {
-						bezirkName := relation.Tags.Find("name")
-						if bezirkName == "" {
-							log.Warn().Msg("bezirk with ID " + fmt.Sprint(relation.ElementID()) + " has empty name field")
-							continue
-						}
-						multiPolygon, err := geo.RelationToMultiPolygon(*relation, processedData.Nodes, processedData.Ways)
-						if err != nil {
-							log.Err(err).Msg("failed converting relation to polygon")
-							continue
-						}
-						polygonMap[bezirkName] = multiPolygon
-					}
-
-					var seekerBezirk string
-					var hiderBezirk string
-
-					for bezirkName, multiPolygon := range polygonMap {
-						if planar.MultiPolygonContains(multiPolygon, seekerPoint) {
-							seekerBezirk = bezirkName
-						}
-						if planar.MultiPolygonContains(multiPolygon, hiderPoint) {
-							hiderBezirk = bezirkName
-						}
-					}
-
-					var description string
-
-					if hiderBezirk == seekerBezirk {
-						description = "Hider is in " + fmt.Sprint(hiderBezirk)
-						for bezirkName, polygon := range polygonMap {
-							if bezirkName != hiderBezirk {
-								fc.Append(geojson.NewFeature(polygon))
-							}
-						}
-					} else {
-						description = "Hider is not in " + fmt.Sprint(seekerBezirk)
-						for bezirkName, polygon := range polygonMap {
-							if bezirkName == seekerBezirk {
-								fc.Append(geojson.NewFeature(polygon))
-							}
-						}
-					}
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Same Bezirk",
-						Description: description,
-					}
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					log.Debug().Msg("seeker bezirk is " + seekerBezirk + " and hider bezirk is " + hiderBezirk)
-
-					err = helpers.CreateCardDraw(db, 3, 1, lobby.ID, w)
-					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					err = helpers.FCToDB(db, lobby, fc)
-					if err != nil {
-						log.Err(err).Msg("failed to save FC to DB while asking same bezirk question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					w.WriteHeader(http.StatusOK)
+				if curseIndex == -1 {
+					w.WriteHeader(http.StatusNotFound)
 					w.Write(nil)
-				})
-				r.Post("/sameOrtsteil", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					fc, err := helpers.FCFromDB(lobby)
-					if err != nil {
-						log.Err(err).Msg("failed to get FC from DB while asking same bezirk question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					var zoneCenter orb.Point
-					zoneCenter[1] = lobby.ZoneCenterLat
-					zoneCenter[0] = lobby.ZoneCenterLon
-
-					var seekerPoint orb.Point
-					seekerPoint[1] = lobby.SeekerLat
-					seekerPoint[0] = lobby.SeekerLon
-
-					var hiderPoint orb.Point
-					hiderPoint[1] = lobby.HiderLat
-					hiderPoint[0] = lobby.HiderLon
-
-					multiPolygonMap := make(map[string]orb.MultiPolygon)
-
-					for _, relation := range processedData.Ortsteile {
-						ortsteilName := relation.Tags.Find("name")
-						if ortsteilName == "" {
-							log.Warn().Msg("ortsteil with ID " + fmt.Sprint(relation.ElementID()) + " has empty name field")
-							continue
-						}
-						multiPolygon, err := geo.RelationToMultiPolygon(*relation, processedData.Nodes, processedData.Ways)
-						if err != nil {
-							log.Err(err).Msg("failed converting relation to polygon")
-							continue
-						}
-						multiPolygonMap[ortsteilName] = multiPolygon
-					}
-
-					var seekerOrtsteil string
-					var hiderOrtsteil string
-
-					for ortsteilName, multiPolygon := range multiPolygonMap {
-						if planar.MultiPolygonContains(multiPolygon, seekerPoint) {
-							seekerOrtsteil = ortsteilName
-						}
-						if planar.MultiPolygonContains(multiPolygon, hiderPoint) {
-							hiderOrtsteil = ortsteilName
-						}
-					}
-
-					var description string
-
-					if hiderOrtsteil == seekerOrtsteil {
-						description = "Hider is in " + hiderOrtsteil
-						for ortsteilName, multiPolygon := range multiPolygonMap {
-							if ortsteilName != hiderOrtsteil {
-								fc.Append(geojson.NewFeature(multiPolygon))
-							} else {
-								log.Debug().Msg("not appending ortsteil " + ortsteilName)
-							}
-						}
-					} else {
-						description = "Hider is not in " + seekerOrtsteil
-						for ortsteilName, polygon := range multiPolygonMap {
-							if ortsteilName == seekerOrtsteil {
-								fc.Append(geojson.NewFeature(polygon))
-								log.Debug().Msg("appending ortsteil " + ortsteilName)
-							}
-						}
-					}
-
-					log.Debug().Msg("seeker ortsteil is " + seekerOrtsteil + " and hider ortsteil is " + hiderOrtsteil)
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Same Ortsteil",
-						Description: description,
-					}
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					err = helpers.CreateCardDraw(db, 3, 1, lobby.ID, w)
-					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					err = helpers.FCToDB(db, lobby, fc)
-					if err != nil {
-						log.Err(err).Msg("failed to save FC to DB while asking same ortsteil question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					w.WriteHeader(http.StatusOK)
+					return
+				}
+				curse := &lobby.PlayedCurseList[curseIndex]
+				if !curse.Resolvable || curse.CurseState != sharedModels.CurseActive {
+					w.WriteHeader(http.StatusConflict)
 					w.Write(nil)
-				})
-				r.Post("/ortsteilLastLetter", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					fc, err := helpers.FCFromDB(lobby)
-					if err != nil {
-						log.Err(err).Msg("failed to get FC from DB while asking last bezirk letter question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					var zoneCenter orb.Point
-					zoneCenter[1] = lobby.ZoneCenterLat
-					zoneCenter[0] = lobby.ZoneCenterLon
-
-					var seekerPoint orb.Point
-					seekerPoint[1] = lobby.SeekerLat
-					seekerPoint[0] = lobby.SeekerLon
-
-					var hiderPoint orb.Point
-					hiderPoint[1] = lobby.HiderLat
-					hiderPoint[0] = lobby.HiderLon
-
-					multiPolygonMap := make(map[string]orb.MultiPolygon)
+					return
+				}
 
-					for _, relation := range processedData.Ortsteile {
-						ortsteilName := relation.Tags.Find("name")
-						if ortsteilName == "" {
-							log.Warn().Msg("ortsteil with ID " + fmt.Sprint(relation.ElementID()) + " has empty name field")
-							continue
-						}
-						multiPolygon, err := geo.RelationToMultiPolygon(*relation, processedData.Nodes, processedData.Ways)
-						if err != nil {
-							log.Err(err).Msg("failed converting relation to polygon")
-							continue
-						}
-						multiPolygonMap[ortsteilName] = multiPolygon
-					}
+				err = curses.SetState(db, curse, sharedModels.CurseResolved, "Seeker resolved the curse")
+				if err != nil {
+					log.Err(err).Msg("failed resolving curse")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				err = sched.Cancel(lobby.ID, models.EventCurseExpiry, curse.ID)
+				if err != nil {
+					log.Err(err).Msg("failed cancelling curse expiry")
+				}
 
-					var seekerOrtsteil string
-					var hiderOrtsteil string
+				w.WriteHeader(http.StatusOK)
+				w.Write(nil)
+			})
+			r.Get("/score", func(w http.ResponseWriter, r *http.Request) {
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Debug().Msg(fmt.Sprint(lobby))
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
 
-					for ortsteilName, multiPolygon := range multiPolygonMap {
-						if planar.MultiPolygonContains(multiPolygon, seekerPoint) {
-							seekerOrtsteil = ortsteilName
-						}
-						if planar.MultiPolygonContains(multiPolygon, hiderPoint) {
-							hiderOrtsteil = ortsteilName
-						}
-					}
+				marshaledScore, err := json.Marshal(scoring.Compute(lobby, time.Now()))
+				if err != nil {
+					log.Err(err).Msg("failed marshaling score")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
 
-					var description string
+				w.WriteHeader(http.StatusOK)
+				w.Write(marshaledScore)
+			})
+			r.Get("/history", func(w http.ResponseWriter, r *http.Request) {
+				userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+				if !isUint {
+					log.Debug().Msg(fmt.Sprint(userID))
+					log.Warn().Msg("failed to convert userID to uint in role selection")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Debug().Msg(fmt.Sprint(lobby))
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				var historyList []sharedModels.HistoryItem
+				for _, dbItem := range lobby.History {
+					historyList = append(historyList, dbItem.DTO())
+				}
 
-					if hiderOrtsteil[len(hiderOrtsteil)-1] == seekerOrtsteil[len(seekerOrtsteil)-1] {
-						description = "Hiders ortsteil ends with " + string(hiderOrtsteil[len(hiderOrtsteil)-1])
-						for ortsteilName, polygon := range multiPolygonMap {
-							if ortsteilName[len(ortsteilName)-1] != hiderOrtsteil[len(hiderOrtsteil)-1] {
-								fc.Append(geojson.NewFeature(polygon))
-							}
-						}
-					} else {
-						description = "Hiders ortsteil doesn't end with " + string(seekerOrtsteil[len(seekerOrtsteil)-1])
-						for ortsteilName, polygon := range multiPolygonMap {
-							if ortsteilName[len(ortsteilName)-1] == seekerOrtsteil[len(seekerOrtsteil)-1] {
-								fc.Append(geojson.NewFeature(polygon))
-							}
-						}
-					}
+				marshaledHistory, err := json.Marshal(historyList)
+				if err != nil {
+					log.Err(err).Msg("failed marshaling history json")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+				}
 
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Ortsteil last letter",
-						Description: description,
-					}
+				w.WriteHeader(http.StatusOK)
+				w.Write(marshaledHistory)
+			})
+			r.Get("/events", func(w http.ResponseWriter, r *http.Request) {
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Debug().Msg(fmt.Sprint(lobby))
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				flusher, isFlusher := w.(http.Flusher)
+				if !isFlusher {
+					log.Warn().Msg("response writer doesn't support flushing, can't stream events")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
 
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				eventChannel, unsubscribe := events.Subscribe(lobby.ID)
+				defer unsubscribe()
 
-					log.Debug().Msg("seeker ortsteil is " + seekerOrtsteil + " and hider ortsteil is " + hiderOrtsteil)
+				w.Header().Set("Content-Type", "text/event-stream")
+				w.Header().Set("Cache-Control", "no-cache")
+				w.Header().Set("Connection", "keep-alive")
+				w.WriteHeader(http.StatusOK)
+				flusher.Flush()
 
-					err = helpers.CreateCardDraw(db, 3, 1, lobby.ID, w)
-					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				// keeps proxies from closing idle connections
+				keepAlive := time.NewTicker(30 * time.Second)
+				defer keepAlive.Stop()
 
-					err = helpers.FCToDB(db, lobby, fc)
-					if err != nil {
-						log.Err(err).Msg("failed to save FC to DB while asking same ortsteil question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
+				for {
+					select {
+					case <-r.Context().Done():
 						return
+					case <-keepAlive.C:
+						fmt.Fprint(w, ": keepalive\n\n")
+						flusher.Flush()
+					case event := <-eventChannel:
+						fmt.Fprint(w, "event: "+string(event.Type)+"\ndata: "+string(event.Data)+"\n\n")
+						flusher.Flush()
 					}
+				}
+			})
+			r.Post("/found", func(w http.ResponseWriter, r *http.Request) {
+				userID, isUint := r.Context().Value(models.UserIDKey).(uint)
+				if !isUint {
+					log.Debug().Msg(fmt.Sprint(userID))
+					log.Warn().Msg("failed to convert userID to uint in hider found request")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Debug().Msg(fmt.Sprint(lobby))
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
 
-					w.WriteHeader(http.StatusOK)
+				if userID != lobby.SeekerID {
+					w.WriteHeader(http.StatusForbidden)
 					w.Write(nil)
-				})
-				r.Post("/closerToMcDonalds", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+					return
+				}
 
-					fc, err := helpers.FCFromDB(lobby)
+				if lobby.Phase != sharedModels.PhaseLocationNarrowing && lobby.Phase != sharedModels.PhaseEndgame {
+					log.Info().Msg("can't find hider in lobby " + lobby.Token + " during phase " + fmt.Sprint(lobby.Phase))
+					w.WriteHeader(http.StatusConflict)
+					w.Write(nil)
+					return
+				}
 
-					lobby, isCloser, distance, err := closerOrFurtherFromObject(lobby, fc, processedData, w, processedData.McDonaldsNodes, processedData.McDonaldsWays)
-					if err != nil {
-						log.Err(err).Msg("failed to get FC from DB while asking last bezirk letter question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-					var description string
-					if isCloser {
-						description = "Hider is closer than " + fmt.Sprint(math.Round(distance)) + "m to a McDonald's"
-					} else {
-						description = "Hider is further than " + fmt.Sprint(math.Round(distance)) + "m from a McDonald's"
-					}
+				var hiderPoint orb.Point
+				hiderPoint[1] = lobby.HiderLat
+				hiderPoint[0] = lobby.HiderLon
 
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Closer to McDonald's",
-						Description: description,
-					}
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				var seekerPoint orb.Point
+				seekerPoint[1] = lobby.SeekerLat
+				seekerPoint[0] = lobby.SeekerLon
 
-					err = helpers.CreateCardDraw(db, 3, 1, lobby.ID, w)
-					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				findDistance := orbGeo.DistanceHaversine(hiderPoint, seekerPoint)
+				if findDistance > lobby.Settings.FoundRadius {
+					log.Info().Msg(fmt.Sprint(sharedModels.ErrHiderNotInReach, ": distance is ", math.Round(findDistance), "m"))
+					w.WriteHeader(http.StatusConflict)
+					w.Write([]byte(sharedModels.ErrHiderNotInReach.Error()))
+					return
+				}
 
-					result = db.Save(&lobby)
-					if result.Error != nil {
-						log.Err(err).Msg("failed saving lobby")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				// stops the clock
+				lobby.FoundTime = time.Now()
+				lobby.HidingTime = lobby.FoundTime.Sub(lobby.RunStartTime)
 
-					w.WriteHeader(http.StatusOK)
-					w.Write(nil)
+				// the score is final once the game is finished, so it gets stored together with the phase
+				err := sched.SetPhaseWith(&lobby, sharedModels.PhaseFinished, map[string]interface{}{
+					"found_time":  lobby.FoundTime,
+					"hiding_time": lobby.HidingTime,
 				})
-				r.Post("/closerToIkea", func(w http.ResponseWriter, r *http.Request) {
-					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
-					if !isLobby {
-						log.Debug().Msg(fmt.Sprint(lobby))
-						log.Warn().Msg("couldn't cast lobby value from context")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					fc, err := helpers.FCFromDB(lobby)
-					if err != nil {
-						log.Err(err).Msg("failed to get FC from DB while asking last bezirk letter question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				if errors.Is(err, scheduler.ErrPhaseChanged) {
+					log.Info().Msg("phase of lobby " + lobby.Token + " changed while the hider was found")
+					w.WriteHeader(http.StatusConflict)
+					w.Write([]byte(err.Error()))
+					return
+				}
+				if err != nil {
+					log.Err(err).Msg("failed finishing game")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
 
-					lobby, isCloser, distance, err := closerOrFurtherFromObject(lobby, fc, processedData, w, map[osm.NodeID]*osm.Node{}, processedData.IkeaWays)
-					var description string
-					if isCloser {
-						description = "Hider is closer than " + fmt.Sprint(math.Round(distance)) + "m to an IKEA"
-					} else {
-						description = "Hider is further than " + fmt.Sprint(math.Round(distance)) + "m from an IKEA"
-					}
+				historyItem := models.HistoryInDB{
+					LobbyID:     lobby.ID,
+					Title:       "Hider found",
+					Description: "Hider was found after " + lobby.HidingTime.Round(time.Second).String(),
+				}
+				result := db.Create(&historyItem)
+				if result.Error != nil {
+					log.Err(result.Error).Msg("failed creating history item for the find")
+				}
+				lobby.History = append(lobby.History, historyItem)
 
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Closer to IKEA",
-						Description: description,
-					}
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				marshaledSummary, err := json.Marshal(gameSummary(lobby))
+				if err != nil {
+					log.Err(err).Msg("failed marshaling game summary")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
 
-					err = helpers.CreateCardDraw(db, 3, 1, lobby.ID, w)
-					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				w.WriteHeader(http.StatusOK)
+				w.Write(marshaledSummary)
+			})
+			r.Get("/summary", func(w http.ResponseWriter, r *http.Request) {
+				lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
+				if !isLobby {
+					log.Debug().Msg(fmt.Sprint(lobby))
+					log.Warn().Msg("couldn't cast lobby value from context")
+					w.WriteHeader(http.StatusInternalServerError)
+					w.Write(nil)
+					return
+				}
 
-					result = db.Save(&lobby)
-					if result.Error != nil {
-						log.Err(err).Msg("failed saving lobby")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
+				if lobby.Phase != sharedModels.PhaseFinished {
+					w.WriteHeader(http.StatusConflict)
+					w.Write(nil)
+					return
+				}
 
-					w.WriteHeader(http.StatusOK)
+				marshaledSummary, err := json.Marshal(gameSummary(lobby))
+				if err != nil {
+					log.Err(err).Msg("failed marshaling game summary")
+					w.WriteHeader(http.StatusInternalServerError)
 					w.Write(nil)
-				})
-				r.Post("/closerToSpree", func(w http.ResponseWriter, r *http.Request) {
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				w.Write(marshaledSummary)
+			})
+			r.Route("/questions", func(r chi.Router) {
+				r.Use(AuthMiddleware(db))
+				r.Get("/closeRoutes", func(w http.ResponseWriter, r *http.Request) {
 					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
 					if !isLobby {
 						log.Debug().Msg(fmt.Sprint(lobby))
@@ -2107,140 +1736,90 @@ func Router(r chi.Router, db *gorm.DB, processedData geo.ProcessedData) {
 						return
 					}
 
-					fc, err := helpers.FCFromDB(lobby)
-					if err != nil {
-						log.Err(err).Msg("failed to get FC from DB while asking last bezirk letter question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					lobby, isCloser, distance, err := closerOrFurtherFromOrbLine(lobby, fc, w, processedData.SpreeLineStrings)
+					var seekerPoint orb.Point
+					seekerPoint[0] = lobby.SeekerLon
+					seekerPoint[1] = lobby.SeekerLat
 
-					var description string
-					if isCloser {
-						description = "Hider is closer than " + fmt.Sprint(math.Round(distance)) + "m to the spree"
-					} else {
-						description = "Hider is further than " + fmt.Sprint(math.Round(distance)) + "m from the spree"
-					}
+					closeRoutes := make(map[osm.RelationID]*osm.Relation)
 
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Closer to Spree",
-						Description: description,
+					for _, routePoint := range processedData.RailRouteIndex.WithinRadius(seekerPoint, 300) {
+						routeID, err := routePoint.ElementID.RelationID()
+						if err != nil {
+							log.Err(err).Msg("element id: " + fmt.Sprint(routePoint.ElementID))
+							continue
+						}
+						closeRoutes[routeID] = processedData.AllRailRoutes[routeID]
 					}
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
+					response := sharedModels.RouteProximityResponse{}
+					for _, route := range closeRoutes {
+						routeItem := sharedModels.RouteDetails{
+							Name:    route.Tags.Find("ref"),
+							RouteID: route.ID,
+						}
+						response.Routes = append(response.Routes, routeItem)
 					}
 
-					err = helpers.CreateCardDraw(db, 3, 1, lobby.ID, w)
+					marshaledReponse, err := json.Marshal(response)
 					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					result = db.Save(&lobby)
-					if result.Error != nil {
-						log.Err(err).Msg("failed saving lobby")
+						log.Err(err).Msg("failed to marshal route proximity reponse")
 						w.WriteHeader(http.StatusInternalServerError)
 						w.Write(nil)
 						return
 					}
 
 					w.WriteHeader(http.StatusOK)
-					w.Write(nil)
+					w.Write(marshaledReponse)
 				})
-				r.Post("/isInHidingZone", func(w http.ResponseWriter, r *http.Request) {
+				r.Post("/thermometer/start", func(w http.ResponseWriter, r *http.Request) {
 					lobby, isLobby := r.Context().Value(models.LobbyKey).(models.Lobby)
 					if !isLobby {
+						log.Debug().Msg(fmt.Sprint(lobby))
 						log.Warn().Msg("couldn't cast lobby value from context")
 						w.WriteHeader(http.StatusInternalServerError)
 						w.Write(nil)
 						return
 					}
 
-					fc, err := helpers.FCFromDB(lobby)
-					if err != nil {
-						log.Err(err).Msg("failed to get FC from DB while asking last bezirk letter question")
-						w.WriteHeader(http.StatusInternalServerError)
-						w.Write(nil)
-						return
-					}
-
-					var zoneCenterPoint orb.Point
-					zoneCenterPoint[0] = lobby.ZoneCenterLon
-					zoneCenterPoint[1] = lobby.ZoneCenterLat
-					var seekerPoint orb.Point
-					seekerPoint[0] = lobby.SeekerLon
-					seekerPoint[1] = lobby.SeekerLat
-
-					var isInHidingZone bool
-					if orbGeo.DistanceHaversine(zoneCenterPoint, seekerPoint) <= sharedModels.HidingZoneRadius {
-						isInHidingZone = true
-					} else {
-						isInHidingZone = false
-					}
-
-					seekerAddr, err := getClosestAdressString(seekerPoint)
+					body, err := helpers.ReadHttpResponse(r.Body)
 					if err != nil {
-						log.Err(err).Msg("failed getting seeker address")
-						w.WriteHeader(http.StatusBadRequest)
-						w.Write(nil)
-						return
-					}
-
-					var description string
-					if isInHidingZone {
-						description = seekerAddr + " is in hiding zone"
-						inverseCircle := helpers.NewInverseCircle(seekerPoint, sharedModels.HidingZoneRadius*2)
-						inverseCircleFeature := geojson.NewFeature(inverseCircle)
-						fc.Append(inverseCircleFeature)
-						lobby.Phase = sharedModels.PhaseEndgame
+						log.Err(err).Msg("failed to read http request of body " + fmt.Sprint(err))
 					}
 
-					historyItem := models.HistoryInDB{
-						LobbyID:     lobby.ID,
-						Title:       "Is in hiding zone",
-						Description: description,
-					}
-					result := db.Create(&historyItem)
-					if result.Error != nil {
-						log.Err(err).Msg("failed creating history item")
-						w.WriteHeader(http.StatusInternalServerError)
+					if lobby.ThermometerDistance != 0 {
+						log.Info().Msg("thermometer already started! can't start another one")
+						w.WriteHeader(http.StatusConflict)
 						w.Write(nil)
 						return
 					}
 
-					err = helpers.CreateCardDraw(db, 1, 1, lobby.ID, w)
+					var thermometerRequest sharedModels.ThermometerRequest
+					err = json.Unmarshal(body, &thermometerRequest)
 					if err != nil {
-						log.Err(result.Error).Msg("failed creating card draw")
-						w.WriteHeader(http.StatusInternalServerError)
+						log.Err(err).Msg("")
+						w.WriteHeader(http.StatusBadRequest)
 						w.Write(nil)
 						return
 					}
+					lobby.ThermometerStartLon = lobby.SeekerLon
+					lobby.ThermometerStartLat = lobby.SeekerLat
+					lobby.ThermometerDistance = thermometerRequest.Distance
 
-					lobby, err = helpers.SaveFC(lobby, fc)
-					if err != nil {
-						log.Err(err).Msg("failed to save fc to lobby struct")
-					}
-
-					result = db.Save(&lobby)
+					result := db.Save(&lobby)
 					if result.Error != nil {
-						log.Err(err).Msg("failed saving lobby")
+						log.Err(result.Error).Msg("")
 						w.WriteHeader(http.StatusInternalServerError)
 						w.Write(nil)
 						return
 					}
-
 					w.WriteHeader(http.StatusOK)
 					w.Write(nil)
 				})
+				questionRegistry.Mount(r, db, questions.Env{
+					Data:      processedData,
+					Geocoder:  geocoder,
+					Scheduler: sched,
+					UploadDir: uploadDir,
+				})
 			})
 		})
 	})
@@ -0,0 +1,23 @@
+package scheduler
+
+import (
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/curses"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+func handleCurseExpiry(s *Scheduler, event models.ScheduledEvent) error {
+	var curse models.Card
+	result := s.db.Where("played_curse_id = ?", event.LobbyID).First(&curse, event.RefID)
+	if result.Error != nil {
+		return result.Error
+	}
+	// the seeker might have resolved it already
+	if curse.CurseState != sharedModels.CurseActive {
+		return nil
+	}
+	log.Info().Msg("curse " + curse.Title + " expired")
+	return curses.SetState(s.db, &curse, sharedModels.CurseExpired, "Curse expired")
+}
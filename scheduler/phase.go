@@ -0,0 +1,130 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/curses"
+	"github.com/jkulzer/fib-server/events"
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+var ErrInvalidPhaseTransition error = errors.New("Invalid game phase transition")
+
+var ErrPhaseChanged error = errors.New("Game phase was changed in the meantime")
+
+// every phase a lobby can move to from its current phase
+var allowedTransitions = map[sharedModels.GamePhase][]sharedModels.GamePhase{
+	sharedModels.PhaseBeforeStart:       {sharedModels.PhaseRun},
+	sharedModels.PhaseRun:               {sharedModels.PhaseLocationNarrowing, sharedModels.PhaseEndgame},
+	sharedModels.PhaseLocationNarrowing: {sharedModels.PhaseEndgame, sharedModels.PhaseFinished},
+	sharedModels.PhaseEndgame:           {sharedModels.PhaseFinished},
+}
+
+// moves the lobby to a new game phase, stores it in the db and schedules the deadlines of the new phase.
+// all phase transitions have to go through here
+func (s *Scheduler) SetPhase(lobby *models.Lobby, phase sharedModels.GamePhase) error {
+	return s.SetPhaseWith(lobby, phase, nil)
+}
+
+// like SetPhase, but also stores the given lobby columns in the same update, e.g. the time the hider was found.
+// the lobby only moves on if its phase didn't change since it was loaded
+func (s *Scheduler) SetPhaseWith(lobby *models.Lobby, phase sharedModels.GamePhase, columns map[string]interface{}) error {
+	if lobby.Phase == phase {
+		return nil
+	}
+	if !slices.Contains(allowedTransitions[lobby.Phase], phase) {
+		log.Warn().Msg("lobby " + lobby.Token + " can't move from phase " + fmt.Sprint(lobby.Phase) + " to " + fmt.Sprint(phase))
+		return ErrInvalidPhaseTransition
+	}
+
+	updates := map[string]interface{}{
+		"phase": phase,
+	}
+	maps.Copy(updates, columns)
+	runStartTime := lobby.RunStartTime
+	if phase == sharedModels.PhaseRun {
+		runStartTime = time.Now()
+		updates["run_start_time"] = runStartTime
+	}
+
+	// the deadlines of the new phase get stored together with it, the timers only change after the commit
+	var runEnd models.ScheduledEvent
+	var cancelledEvents []models.ScheduledEvent
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Lobby{}).Where("id = ? AND phase = ?", lobby.ID, lobby.Phase).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPhaseChanged
+		}
+
+		switch phase {
+		case sharedModels.PhaseRun:
+			runEnd = models.ScheduledEvent{
+				LobbyID: lobby.ID,
+				Type:    models.EventRunEnd,
+				FireAt:  runStartTime.Add(lobby.Settings.RunDuration),
+			}
+			return tx.Create(&runEnd).Error
+		case sharedModels.PhaseEndgame:
+			result = tx.Where("lobby_id = ? AND type = ? AND ref_id = ?", lobby.ID, models.EventRunEnd, 0).Find(&cancelledEvents)
+		case sharedModels.PhaseFinished:
+			result = tx.Where("lobby_id = ?", lobby.ID).Find(&cancelledEvents)
+		default:
+			return nil
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		for _, event := range cancelledEvents {
+			result = tx.Delete(&event)
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	lobby.Phase = phase
+	lobby.RunStartTime = runStartTime
+	s.stopTimers(cancelledEvents)
+	if runEnd.ID != 0 {
+		s.arm(runEnd)
+	}
+	events.Publish(lobby.ID, sharedModels.EventPhaseChange, sharedModels.PhaseResponse{Phase: phase})
+
+	err = curses.OnPhaseChange(s.db, *lobby)
+	if err != nil {
+		log.Err(err).Msg("failed running curse phase hooks")
+	}
+
+	log.Info().Msg("lobby " + lobby.Token + " moved to phase " + fmt.Sprint(phase))
+	return nil
+}
+
+func handleRunEnd(s *Scheduler, event models.ScheduledEvent) error {
+	log.Info().Msg("Hiding Time Finished")
+	var lobby models.Lobby
+	result := s.db.First(&lobby, event.LobbyID)
+	if result.Error != nil {
+		return result.Error
+	}
+	// the lobby might have moved on already, e.g. if the seeker found the hiding zone early
+	if lobby.Phase != sharedModels.PhaseRun {
+		return nil
+	}
+	return s.SetPhase(&lobby, sharedModels.PhaseLocationNarrowing)
+}
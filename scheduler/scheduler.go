@@ -0,0 +1,178 @@
+package scheduler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/jkulzer/fib-server/models"
+)
+
+// gets called when a scheduled event is due
+type Handler func(s *Scheduler, event models.ScheduledEvent) error
+
+// the scheduler keeps every game deadline in the db and arms an in-memory timer for it.
+// after a restart, Recover rearms all deadlines that haven't fired yet
+type Scheduler struct {
+	db       *gorm.DB
+	mutex    sync.Mutex
+	handlers map[models.ScheduledEventType]Handler
+	timers   map[uint]*time.Timer
+}
+
+func New(db *gorm.DB) *Scheduler {
+	s := &Scheduler{
+		db:       db,
+		handlers: make(map[models.ScheduledEventType]Handler),
+		timers:   make(map[uint]*time.Timer),
+	}
+	s.Handle(models.EventRunEnd, handleRunEnd)
+	s.Handle(models.EventCurseExpiry, handleCurseExpiry)
+	return s
+}
+
+func (s *Scheduler) DB() *gorm.DB {
+	return s.db
+}
+
+// registers the function that gets called when an event of the given type fires
+func (s *Scheduler) Handle(eventType models.ScheduledEventType, handler Handler) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.handlers[eventType] = handler
+}
+
+// stores the deadline in the db and arms a timer for it
+func (s *Scheduler) Schedule(lobbyID uint, eventType models.ScheduledEventType, refID uint, fireAt time.Time) error {
+	event, err := s.ScheduleIn(s.db, lobbyID, eventType, refID, fireAt)
+	if err != nil {
+		return err
+	}
+	s.arm(event)
+	return nil
+}
+
+// stores the deadline together with the other writes of the transaction.
+// the timer only gets armed by Arm once the transaction is committed
+func (s *Scheduler) ScheduleIn(tx *gorm.DB, lobbyID uint, eventType models.ScheduledEventType, refID uint, fireAt time.Time) (models.ScheduledEvent, error) {
+	event := models.ScheduledEvent{
+		LobbyID: lobbyID,
+		Type:    eventType,
+		RefID:   refID,
+		FireAt:  fireAt,
+	}
+	result := tx.Create(&event)
+	if result.Error != nil {
+		return models.ScheduledEvent{}, result.Error
+	}
+	return event, nil
+}
+
+// arms the timer of an event that was stored with ScheduleIn
+func (s *Scheduler) Arm(event models.ScheduledEvent) {
+	s.arm(event)
+}
+
+// removes all pending events of a type for the given lobby and reference
+func (s *Scheduler) Cancel(lobbyID uint, eventType models.ScheduledEventType, refID uint) error {
+	var events []models.ScheduledEvent
+	result := s.db.Where("lobby_id = ? AND type = ? AND ref_id = ?", lobbyID, eventType, refID).Find(&events)
+	if result.Error != nil {
+		return result.Error
+	}
+	return s.cancelEvents(events)
+}
+
+// removes all pending events of a lobby, e.g. when the game is over
+func (s *Scheduler) CancelLobby(lobbyID uint) error {
+	var events []models.ScheduledEvent
+	result := s.db.Where("lobby_id = ?", lobbyID).Find(&events)
+	if result.Error != nil {
+		return result.Error
+	}
+	return s.cancelEvents(events)
+}
+
+func (s *Scheduler) cancelEvents(events []models.ScheduledEvent) error {
+	s.stopTimers(events)
+
+	for _, event := range events {
+		result := s.db.Delete(&event)
+		if result.Error != nil {
+			return result.Error
+		}
+	}
+	return nil
+}
+
+// stops the timers of events that got removed from the db
+func (s *Scheduler) stopTimers(events []models.ScheduledEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, event := range events {
+		if timer, ok := s.timers[event.ID]; ok {
+			timer.Stop()
+			delete(s.timers, event.ID)
+		}
+	}
+}
+
+// loads all events that haven't fired yet from the db and arms them.
+// events whose deadline passed while the server was down fire immediately
+func (s *Scheduler) Recover() error {
+	var events []models.ScheduledEvent
+	result := s.db.Find(&events)
+	if result.Error != nil {
+		return result.Error
+	}
+	for _, event := range events {
+		s.arm(event)
+	}
+	log.Info().Msg("recovered " + fmt.Sprint(len(events)) + " scheduled events")
+	return nil
+}
+
+func (s *Scheduler) arm(event models.ScheduledEvent) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	eventID := event.ID
+	s.timers[eventID] = time.AfterFunc(time.Until(event.FireAt), func() {
+		s.fire(eventID)
+	})
+}
+
+func (s *Scheduler) fire(eventID uint) {
+	s.mutex.Lock()
+	delete(s.timers, eventID)
+	s.mutex.Unlock()
+
+	// reloads the event in case it got cancelled in the meantime
+	var event models.ScheduledEvent
+	result := s.db.First(&event, eventID)
+	if result.Error != nil {
+		return
+	}
+
+	s.mutex.Lock()
+	handler, ok := s.handlers[event.Type]
+	s.mutex.Unlock()
+	if !ok {
+		log.Warn().Msg("no handler for scheduled event type " + fmt.Sprint(event.Type))
+	} else {
+		err := handler(s, event)
+		if err != nil {
+			log.Err(err).Msg("failed handling scheduled event " + fmt.Sprint(event.ID) + " for lobby " + fmt.Sprint(event.LobbyID))
+			return
+		}
+	}
+
+	result = s.db.Delete(&event)
+	if result.Error != nil {
+		log.Err(result.Error).Msg("failed deleting fired scheduled event")
+	}
+}
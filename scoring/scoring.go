@@ -0,0 +1,54 @@
+package scoring
+
+import (
+	"time"
+
+	"github.com/jkulzer/fib-server/models"
+	"github.com/jkulzer/fib-server/sharedModels"
+)
+
+// computes the score of the hider. before the game is finished, the elapsed time runs up to now
+func Compute(lobby models.Lobby, now time.Time) sharedModels.ScoreBreakdown {
+	var breakdown sharedModels.ScoreBreakdown
+
+	switch {
+	case lobby.Phase == sharedModels.PhaseFinished:
+		breakdown.ElapsedTime = lobby.HidingTime
+		breakdown.Final = true
+	case lobby.RunStartTime.IsZero() || lobby.Phase == sharedModels.PhaseBeforeStart:
+		breakdown.ElapsedTime = 0
+	default:
+		breakdown.ElapsedTime = now.Sub(lobby.RunStartTime)
+	}
+
+	// time bonus cards only count while they are still in the hand of the hider
+	for _, card := range lobby.HiderDeck {
+		if card.Type != sharedModels.TimebonusCard {
+			continue
+		}
+		breakdown.BonusCards = append(breakdown.BonusCards, sharedModels.ScoreItem{
+			Title: card.Title,
+			Time:  card.BonusTime,
+		})
+	}
+
+	for _, curse := range lobby.PlayedCurseList {
+		if !curse.PenaltyApplied || curse.PenaltyTime == 0 {
+			continue
+		}
+		breakdown.CurseAdjustments = append(breakdown.CurseAdjustments, sharedModels.ScoreItem{
+			Title: curse.Title,
+			Time:  curse.PenaltyTime,
+		})
+	}
+
+	breakdown.Total = breakdown.ElapsedTime
+	for _, item := range breakdown.BonusCards {
+		breakdown.Total += item.Time
+	}
+	for _, item := range breakdown.CurseAdjustments {
+		breakdown.Total += item.Time
+	}
+
+	return breakdown
+}
@@ -5,3 +5,31 @@ import (
 )
 
 var ErrHiderLocationNotInZone error = errors.New("Hider location must be in zone")
+
+var ErrNotAPlayer error = errors.New("User is neither hider nor seeker of the lobby")
+
+var ErrHiderNotInReach error = errors.New("Hider is too far away from seeker")
+
+var ErrInvalidRunDuration error = errors.New("Run duration must be between 5 minutes and 4 hours")
+
+var ErrInvalidHidingZoneRadius error = errors.New("Hiding zone radius must be between 100m and 2000m")
+
+var ErrInvalidFoundRadius error = errors.New("Found radius must be at least 5m and not larger than the hiding zone radius")
+
+var ErrInvalidMaxHandSize error = errors.New("Max hand size must be between 1 and 20 cards")
+
+var ErrFeatureNotInRegion error = errors.New("Feature isn't available in the region of this server")
+
+var ErrSettingsLocked error = errors.New("Settings can only be changed before the game starts")
+
+var ErrInvalidQuestionParameter error = errors.New("Invalid question parameter")
+
+var ErrNoOpenQuestion error = errors.New("There is no question waiting for the answer of the hider")
+
+var ErrNoRandomQuestion error = errors.New("There is no other question of the same category")
+
+var ErrInvalidDuplicateTarget error = errors.New("Duplicate must copy another card of the hand")
+
+var ErrInvalidZoneCenter error = errors.New("The new hiding zone must be centered on a station in the game area")
+
+var ErrUnknownDeckPack error = errors.New("Deck pack doesn't exist on this server")
@@ -1,6 +1,8 @@
 package sharedModels
 
 import (
+	"encoding/json"
+
 	"github.com/google/uuid"
 
 	"github.com/jkulzer/osm"
@@ -56,6 +58,94 @@ type RouteDetails struct {
 	RouteID osm.RelationID
 }
 
+type QuestionCategory string
+
+const (
+	CategoryMatching    QuestionCategory = "matching"
+	CategoryMeasuring   QuestionCategory = "measuring"
+	CategoryThermometer QuestionCategory = "thermometer"
+	CategoryRadar       QuestionCategory = "radar"
+	CategoryTentacle    QuestionCategory = "tentacle"
+	CategoryPhoto       QuestionCategory = "photo"
+)
+
+var QuestionCategories = []QuestionCategory{
+	CategoryMatching,
+	CategoryMeasuring,
+	CategoryThermometer,
+	CategoryRadar,
+	CategoryTentacle,
+	CategoryPhoto,
+}
+
+// the cards the hider draws and keeps for answering a question
+type QuestionReward struct {
+	CardsToDraw uint
+	CardsToPick uint
+}
+
+type QuestionInfo struct {
+	ID string
+	// relative to /lobby/{index}/questions, with chi URL parameters like {radius}
+	Path     string
+	Title    string
+	Category QuestionCategory
+	Reward   QuestionReward
+}
+
+type QuestionState string
+
+const (
+	// waiting for the answer of the hider, the seeker can't ask another question meanwhile
+	QuestionAsked    QuestionState = "asked"
+	QuestionAnswered QuestionState = "answered"
+	// the hider didn't answer before the deadline
+	QuestionExpired QuestionState = "expired"
+	QuestionVetoed  QuestionState = "vetoed"
+)
+
+// a question that was asked in the lobby. photo questions get answered by uploading to /lobby/{index}/questions/{ID}/photo,
+// other questions of the hider by posting an AnswerQuestionRequest to /lobby/{index}/questions/{ID}/answer
+type AskedQuestion struct {
+	ID         uint
+	QuestionID string
+	Title      string
+	Category   QuestionCategory
+	State      QuestionState
+	AskedAt    time.Time
+	Deadline   time.Time
+	Answer     string
+	PhotoID    uint
+}
+
+type AnswerQuestionRequest struct {
+	Answer string
+}
+
+type PhotoUploadResponse struct {
+	PhotoID uint
+}
+
+type AskedQuestionListResponse struct {
+	Questions []AskedQuestion
+}
+
+// a deck pack the lobby creator can choose in the settings
+type DeckPackInfo struct {
+	Key         string
+	Name        string
+	Description string
+	CardCount   int
+}
+
+type DeckPackListResponse struct {
+	Packs []DeckPackInfo
+}
+
+type QuestionListResponse struct {
+	Questions []QuestionInfo
+}
+
 type GamePhase int
 
 const (
@@ -71,6 +161,24 @@ type PhaseResponse struct {
 	Phase GamePhase
 }
 
+type LobbyEventType string
+
+const (
+	EventPhaseChange LobbyEventType = "phase"
+	EventHistory     LobbyEventType = "history"
+	EventCardDraw    LobbyEventType = "cardDraw"
+	EventCursePlayed LobbyEventType = "curse"
+	EventCurseUpdate LobbyEventType = "curseUpdate"
+	EventMapUpdate   LobbyEventType = "map"
+	EventQuestion    LobbyEventType = "question"
+)
+
+// gets pushed to clients over the lobby event stream
+type LobbyEvent struct {
+	Type LobbyEventType
+	Data json.RawMessage
+}
+
 type ReadinessResponse struct {
 	Ready bool
 }
@@ -90,6 +198,8 @@ type TimeResponse struct {
 type HistoryItem struct {
 	Title       string
 	Description string
+	// 0 if there is no photo, otherwise it can be downloaded from /lobby/{index}/questions/photos/{PhotoID}
+	PhotoID uint
 }
 
 type History []HistoryItem
@@ -98,9 +208,78 @@ type HistoryResponse struct {
 	History History
 }
 
-var RunDuration time.Duration = 45 * time.Minute
+// rules of a single lobby. can only be changed before the game starts
+type GameSettings struct {
+	RunDuration      time.Duration
+	HidingZoneRadius float64
+	// how close the seeker has to be to the hider to claim the find
+	FoundRadius float64
+	MaxHandSize int
+	// the key of the deck pack the cards get drawn from
+	DeckPack string
+}
+
+// the pack that replaces the old built-in card list
+const DefaultDeckPack = "default"
 
-var HidingZoneRadius float64 = 500.0
+func DefaultGameSettings() GameSettings {
+	return GameSettings{
+		RunDuration:      45 * time.Minute,
+		HidingZoneRadius: 500.0,
+		FoundRadius:      50.0,
+		MaxHandSize:      6,
+		DeckPack:         DefaultDeckPack,
+	}
+}
+
+func (s GameSettings) Validate() error {
+	if s.RunDuration < 5*time.Minute || s.RunDuration > 4*time.Hour {
+		return ErrInvalidRunDuration
+	}
+	if s.HidingZoneRadius < 100 || s.HidingZoneRadius > 2000 {
+		return ErrInvalidHidingZoneRadius
+	}
+	if s.FoundRadius < 5 || s.FoundRadius > s.HidingZoneRadius {
+		return ErrInvalidFoundRadius
+	}
+	if s.MaxHandSize < 1 || s.MaxHandSize > 20 {
+		return ErrInvalidMaxHandSize
+	}
+	return nil
+}
+
+type LocationStreamResponse struct {
+	Accepted bool
+	// set when the location got rejected, e.g. because the hider left the hiding zone
+	Warning string
+}
+
+type GameSummary struct {
+	RunStartTime   time.Time
+	FoundTime      time.Time
+	HidingTime     time.Duration
+	HiderLocation  orb.Point
+	SeekerLocation orb.Point
+	// distance between hider and seeker at the time of the find
+	FindDistance float64
+	CursesPlayed int
+	History      History
+	Score        ScoreBreakdown
+}
+
+type ScoreItem struct {
+	Title string
+	Time  time.Duration
+}
+
+type ScoreBreakdown struct {
+	ElapsedTime      time.Duration
+	BonusCards       []ScoreItem
+	CurseAdjustments []ScoreItem
+	Total            time.Duration
+	// false while the game is still running
+	Final bool
+}
 
 type LocationRequest struct {
 	Location orb.Point
@@ -111,6 +290,14 @@ var rightBound float64 = 15
 var topBound float64 = 54
 var bottomBound float64 = 51
 
+// sets the box around the game area. gets called once when the region is loaded
+func SetWorldBounds(left float64, right float64, top float64, bottom float64) {
+	leftBound = left
+	rightBound = right
+	topBound = top
+	bottomBound = bottom
+}
+
 func LeftTopPoint() orb.Point {
 	var leftTopPoint orb.Point
 	leftTopPoint[0] = leftBound
@@ -162,8 +349,6 @@ type CardDraw struct {
 	CardsToPick uint
 }
 
-var MaxHandSize int = 6
-
 type CardIDList struct {
 	CardIDList []uint
 }